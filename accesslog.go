@@ -0,0 +1,147 @@
+package jsonapi
+
+import (
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// AccessLogEntry is a record of a served request
+//
+//easyjson:json
+type AccessLogEntry struct {
+	Time      time.Time     `json:"time"`
+	Method    string        `json:"method"`
+	Path      string        `json:"path"`
+	Route     string        `json:"route"` // registered route path
+	Status    int           `json:"status"`
+	Duration  time.Duration `json:"duration"` // nanoseconds in json
+	Bytes     int           `json:"bytes"`    // response body size, 0 for streams
+	RemoteIP  string        `json:"remoteIp"`
+	RequestID string        `json:"requestId,omitempty"`
+	Subject   string        `json:"subject,omitempty"`
+	Error     string        `json:"error,omitempty"` // handler error, it is not sent to the client
+}
+
+// AccessLogger records served requests
+type AccessLogger interface {
+	Log(e *AccessLogEntry)
+}
+
+// NewJSONAccessLogger returns an AccessLogger writing
+// entries to w as json lines
+func NewJSONAccessLogger(w io.Writer) AccessLogger {
+	return &jsonAccessLogger{w: w, mu: new(sync.Mutex)}
+}
+
+type jsonAccessLogger struct {
+	w  io.Writer
+	mu *sync.Mutex
+}
+
+// Log implements AccessLogger
+func (l *jsonAccessLogger) Log(e *AccessLogEntry) {
+	b, err := e.MarshalJSON()
+	if err != nil {
+		return
+	}
+	b = append(b, '\n')
+	l.mu.Lock()
+	l.w.Write(b)
+	l.mu.Unlock()
+}
+
+// AccessLogOption configures access logging
+type AccessLogOption func(*accessLog)
+
+// AccessLogSample logs only a rate (0..1] of successful requests,
+// requests with status >= 400 are always logged
+func AccessLogSample(rate float64) AccessLogOption {
+	return func(l *accessLog) {
+		l.rate = rate
+	}
+}
+
+// AccessLogSkip skips requests to routes registered with
+// the paths, for example: AccessLogSkip("/health")
+func AccessLogSkip(paths ...string) AccessLogOption {
+	return func(l *accessLog) {
+		for _, p := range paths {
+			l.skip[p] = true
+		}
+	}
+}
+
+// AccessLogSkipFunc skips requests for which fn returns TRUE
+func AccessLogSkipFunc(fn func(*Ctx) bool) AccessLogOption {
+	return func(l *accessLog) {
+		l.skipFuncs = append(l.skipFuncs, fn)
+	}
+}
+
+type accessLog struct {
+	logger    AccessLogger
+	rate      float64
+	skip      map[string]bool
+	skipFuncs []func(*Ctx) bool
+}
+
+// SetAccessLogger sets a logger recording requests served by routes,
+// nil disables access logging
+func (s *Server) SetAccessLogger(logger AccessLogger, opts ...AccessLogOption) *Server {
+	if logger == nil {
+		s.accessLog = nil
+		return s
+	}
+	l := &accessLog{logger: logger, rate: 1, skip: map[string]bool{}}
+	for _, opt := range opts {
+		opt(l)
+	}
+	s.accessLog = l
+	return s
+}
+
+func (l *accessLog) log(c *Ctx, rt *route, start time.Time) {
+	if l.skip[rt.path] {
+		return
+	}
+	status := c.Response.StatusCode()
+	if status < StatusBadRequest && l.rate < 1 && rand.Float64() >= l.rate {
+		return
+	}
+	for _, fn := range l.skipFuncs {
+		if fn(c) {
+			return
+		}
+	}
+	l.logger.Log(&AccessLogEntry{
+		Time:      start,
+		Method:    string(c.Method()),
+		Path:      string(c.Path()),
+		Route:     rt.path,
+		Status:    status,
+		Duration:  time.Since(start),
+		Bytes:     responseSize(c),
+		RemoteIP:  c.RemoteIP().String(),
+		RequestID: c.RequestID(),
+		Subject:   c.Subject(),
+		Error:     errString(c.errCause()),
+	})
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// responseSize returns the response body size, body streams are
+// not read as reading them would consume the stream
+func responseSize(c *Ctx) int {
+	if c.Response.IsBodyStream() {
+		return 0
+	}
+	return len(c.Response.Body())
+}
@@ -0,0 +1,118 @@
+package jsonapi
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestAccessLog(t *testing.T) {
+	suite.Run(t, new(AccessLogTestSuite))
+}
+
+type AccessLogTestSuite struct {
+	suite.Suite
+}
+
+type syncBuffer struct {
+	bytes.Buffer
+	mu sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.Buffer.Write(p)
+}
+
+func (b *syncBuffer) entries() []*AccessLogEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var res []*AccessLogEntry
+	sc := bufio.NewScanner(bytes.NewReader(b.Bytes()))
+	for sc.Scan() {
+		e := new(AccessLogEntry)
+		if e.UnmarshalJSON(sc.Bytes()) == nil {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+func (t *AccessLogTestSuite) serve(s *Server, uris ...string) {
+	ln := fasthttputil.NewInmemoryListener()
+	go s.SetListener(ln).Listen()
+	defer ln.Close()
+	cl := NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() })
+	for _, uri := range uris {
+		_, err := cl.Get(uri)
+		t.NoError(err)
+	}
+}
+
+func (t *AccessLogTestSuite) server(w *syncBuffer, opts ...AccessLogOption) *Server {
+	s := NewServer().
+		SetAccessLogger(NewJSONAccessLogger(w), opts...).
+		Get("/animals/:id", func(ctx *Ctx) {
+			ctx.SetSubject("user-1")
+			ctx.OK(ctx.GetParamString("id"))
+		}).
+		Get("/health", func(ctx *Ctx) { ctx.OK("ok") }).
+		Get("/stream", func(ctx *Ctx) {
+			ctx.SetBodyStreamWriter(func(w *bufio.Writer) { w.WriteString("streamed") })
+		}).
+		Get("/fail", func(ctx *Ctx) { ctx.ErrInternalServerError(NewErrorString("fail")) }).
+		ControllerMethod(MethodGet, "/boom", func(*Ctx) *Result { return &Result{Cause: errors.New("db is down")} })
+	return s
+}
+
+func (t *AccessLogTestSuite) TestAccessLog() {
+	w := new(syncBuffer)
+	t.serve(t.server(w), "/animals/1?x=1", "/fail", "/boom")
+	entries := w.entries()
+	t.Len(entries, 3)
+	e := entries[0]
+	t.Equal(MethodGet, e.Method)
+	t.Equal("/animals/1", e.Path)
+	t.Equal("/animals/:id", e.Route)
+	t.Equal(StatusOK, e.Status)
+	t.Equal(3, e.Bytes)
+	t.True(e.Duration > 0)
+	t.False(e.Time.IsZero())
+	t.NotEmpty(e.RemoteIP)
+	t.Len(e.RequestID, 32)
+	t.Equal("user-1", e.Subject)
+	t.Equal(StatusInternalServerError, entries[1].Status)
+	t.Empty(entries[1].Subject)
+	t.Empty(entries[1].Error)
+	t.Equal(StatusInternalServerError, entries[2].Status)
+	t.Equal("db is down", entries[2].Error)
+
+	// body streams are not read
+	w = new(syncBuffer)
+	t.serve(t.server(w), "/stream")
+	entries = w.entries()
+	t.Len(entries, 1)
+	t.Equal(0, entries[0].Bytes)
+}
+
+func (t *AccessLogTestSuite) TestAccessLogSkip() {
+	w := new(syncBuffer)
+	s := t.server(w,
+		AccessLogSkip("/health"),
+		AccessLogSample(0),
+		AccessLogSkipFunc(func(ctx *Ctx) bool {
+			return ctx.QueryArgs().Has("nolog")
+		}))
+	t.serve(s, "/health", "/animals/1", "/fail", "/fail?nolog")
+	entries := w.entries()
+	t.Len(entries, 1)
+	t.Equal("/fail", entries[0].Path)
+}
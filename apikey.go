@@ -0,0 +1,293 @@
+package jsonapi
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// DefaultAPIKeyHeader is the default header of API keys
+	DefaultAPIKeyHeader = "X-API-Key"
+
+	apiKeyKey = "jsonapi.apikey"
+)
+
+var (
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	ErrAPIKeyExpired = errors.New("api key is expired")
+	ErrAPIKeyStore   = errors.New("api key store is unavailable")
+)
+
+// APIKey is a stored API key, the key itself is never
+// stored, only its HashAPIKey hash
+type APIKey struct {
+	ID        string            `json:"id"`
+	Hash      string            `json:"hash"`
+	Subject   string            `json:"subject"`
+	Roles     []string          `json:"roles,omitempty"`
+	Scopes    []string          `json:"scopes,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	ExpiresAt time.Time         `json:"expiresAt,omitempty"` // zero for keys without expiry
+}
+
+// Expired returns TRUE if the key is expired at t
+func (k *APIKey) Expired(t time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !t.Before(k.ExpiresAt)
+}
+
+// HashAPIKey returns the hex encoded SHA-256 hash of key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateAPIKey returns a new random key and its hash
+func GenerateAPIKey() (key string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key = base64.RawURLEncoding.EncodeToString(b)
+	return key, HashAPIKey(key), nil
+}
+
+// APIKeyStore looks up API keys by hash, a nil key
+// with a nil error is returned for unknown keys
+type APIKeyStore interface {
+	LookupAPIKey(hash string) (*APIKey, error)
+}
+
+// NewMemoryKeyStore creates an in-memory APIKeyStore of keys
+func NewMemoryKeyStore(keys ...*APIKey) *MemoryKeyStore {
+	s := &MemoryKeyStore{mu: new(sync.RWMutex)}
+	s.set(keys)
+	return s
+}
+
+// MemoryKeyStore is an in-memory APIKeyStore
+type MemoryKeyStore struct {
+	keys map[string]*APIKey // by hash
+	mu   *sync.RWMutex
+}
+
+// LookupAPIKey implements APIKeyStore
+func (s *MemoryKeyStore) LookupAPIKey(hash string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys[hash], nil
+}
+
+// Add adds keys replacing keys with the same hash
+func (s *MemoryKeyStore) Add(keys ...*APIKey) {
+	s.mu.Lock()
+	for _, k := range keys {
+		s.keys[k.Hash] = k
+	}
+	s.mu.Unlock()
+}
+
+// Remove removes keys by id
+func (s *MemoryKeyStore) Remove(ids ...string) {
+	s.mu.Lock()
+	for hash, k := range s.keys {
+		if contains(ids, k.ID) {
+			delete(s.keys, hash)
+		}
+	}
+	s.mu.Unlock()
+}
+
+// Rotate adds next and expires the key with id old after grace,
+// both keys are accepted during the grace period
+func (s *MemoryKeyStore) Rotate(old string, next *APIKey, grace time.Duration) {
+	exp := time.Now().Add(grace)
+	s.mu.Lock()
+	for hash, k := range s.keys {
+		if k.ID == old && (k.ExpiresAt.IsZero() || exp.Before(k.ExpiresAt)) {
+			c := *k
+			c.ExpiresAt = exp
+			s.keys[hash] = &c
+		}
+	}
+	s.keys[next.Hash] = next
+	s.mu.Unlock()
+}
+
+// Keys returns stored keys
+func (s *MemoryKeyStore) Keys() []*APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := make([]*APIKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		res = append(res, k)
+	}
+	return res
+}
+
+func (s *MemoryKeyStore) set(keys []*APIKey) {
+	m := make(map[string]*APIKey, len(keys))
+	for _, k := range keys {
+		m[k.Hash] = k
+	}
+	s.mu.Lock()
+	s.keys = m
+	s.mu.Unlock()
+}
+
+// NewFileKeyStore creates an APIKeyStore of keys read from a JSON
+// file containing an array of APIKey
+func NewFileKeyStore(path string) (*FileKeyStore, error) {
+	s := &FileKeyStore{MemoryKeyStore: NewMemoryKeyStore(), path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// FileKeyStore is a MemoryKeyStore loaded from a JSON file
+type FileKeyStore struct {
+	*MemoryKeyStore
+	path string
+}
+
+// Reload replaces stored keys with keys of the file,
+// stored keys are kept if the file can't be read
+func (s *FileKeyStore) Reload() error {
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var keys []*APIKey
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if k.Hash == "" {
+			return errors.New("api key " + k.ID + " has no hash")
+		}
+	}
+	s.set(keys)
+	return nil
+}
+
+// APIKeyOption configures APIKeyAuth
+type APIKeyOption func(*APIKeyAuth)
+
+// APIKeyHeader reads keys from header instead of DefaultAPIKeyHeader
+func APIKeyHeader(header string) APIKeyOption {
+	return func(a *APIKeyAuth) {
+		a.header = header
+	}
+}
+
+// APIKeyQuery also reads keys from query argument name
+// if the header is not set
+func APIKeyQuery(name string) APIKeyOption {
+	return func(a *APIKeyAuth) {
+		a.query = name
+	}
+}
+
+// NewAPIKeyAuth creates an API key authenticator verifying keys of store
+func NewAPIKeyAuth(store APIKeyStore, opts ...APIKeyOption) *APIKeyAuth {
+	a := &APIKeyAuth{store: store, header: DefaultAPIKeyHeader, now: time.Now}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// APIKeyAuth verifies API keys sent in a header or query argument
+type APIKeyAuth struct {
+	store  APIKeyStore
+	header string
+	query  string
+	now    func() time.Time
+}
+
+// Authenticate implements Authenticator
+func (a *APIKeyAuth) Authenticate(ctx *Ctx) (*Principal, error) {
+	key := ctx.GetHeader(a.header)
+	if key == "" && a.query != "" {
+		key = string(ctx.QueryArgs().Peek(a.query))
+		if key != "" {
+			a.redactQuery(ctx)
+		}
+	}
+	if key == "" {
+		return nil, ErrUnauthorized
+	}
+	k, err := a.store.LookupAPIKey(HashAPIKey(key))
+	if err != nil {
+		// store failures aren't invalid keys, the cause is not sent to the client
+		e := NewError(ErrAPIKeyStore, StatusServiceUnavailable)
+		e.cause = err
+		return nil, e
+	}
+	if k == nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if k.Expired(a.now()) {
+		return nil, ErrAPIKeyExpired
+	}
+	ctx.SetUserValue(apiKeyKey, k)
+	return &Principal{
+		Subject: k.Subject,
+		Method:  "apikey",
+		Roles:   k.Roles,
+		Scopes:  k.Scopes,
+	}, nil
+}
+
+// redactQuery replaces the key of the query argument in the target
+// of the request span, so keys aren't exported with traces
+func (a *APIKeyAuth) redactQuery(ctx *Ctx) {
+	span := ctx.Span()
+	if span == nil {
+		return
+	}
+	args := fasthttp.AcquireArgs()
+	defer fasthttp.ReleaseArgs(args)
+	ctx.QueryArgs().CopyTo(args)
+	args.Set(a.query, "REDACTED")
+	span.SetAttribute("http.target", string(ctx.URI().PathOriginal())+"?"+args.String())
+}
+
+// AuthFunc returns a ServerAuthFunc accepting requests with valid
+// keys, it sets the key subject as the request subject
+func (a *APIKeyAuth) AuthFunc() ServerAuthFunc {
+	return func(ctx *Ctx) bool {
+		p, err := a.Authenticate(ctx)
+		if err != nil {
+			return false
+		}
+		ctx.SetSubject(p.Subject)
+		return true
+	}
+}
+
+// APIKey returns the verified API key of the request or nil
+func (c *Ctx) APIKey() *APIKey {
+	k, _ := c.UserValue(apiKeyKey).(*APIKey)
+	return k
+}
+
+// APIKeyClientAuth returns a ClientAuthFunc sending key in
+// header, DefaultAPIKeyHeader is used if header is empty
+func APIKeyClientAuth(header string, key string) ClientAuthFunc {
+	if header == "" {
+		header = DefaultAPIKeyHeader
+	}
+	return func(r *Request) {
+		r.SetHeader(header, key)
+	}
+}
@@ -0,0 +1,173 @@
+package jsonapi
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestAPIKey(t *testing.T) {
+	suite.Run(t, new(APIKeyTestSuite))
+}
+
+type APIKeyTestSuite struct {
+	suite.Suite
+}
+
+func (t *APIKeyTestSuite) TestGenerate() {
+	key, hash, err := GenerateAPIKey()
+	t.NoError(err)
+	t.Len(key, 43)
+	t.Equal(HashAPIKey(key), hash)
+	other, _, err := GenerateAPIKey()
+	t.NoError(err)
+	t.NotEqual(key, other)
+}
+
+func (t *APIKeyTestSuite) TestMemoryKeyStore() {
+	s := NewMemoryKeyStore(
+		&APIKey{ID: "a", Hash: HashAPIKey("key-a")},
+		&APIKey{ID: "b", Hash: HashAPIKey("key-b")},
+	)
+	k, err := s.LookupAPIKey(HashAPIKey("key-a"))
+	t.NoError(err)
+	t.Equal("a", k.ID)
+
+	s.Rotate("a", &APIKey{ID: "c", Hash: HashAPIKey("key-c")}, time.Minute)
+	k, _ = s.LookupAPIKey(HashAPIKey("key-a"))
+	t.False(k.Expired(time.Now()))
+	t.True(k.Expired(time.Now().Add(time.Minute)))
+	k, _ = s.LookupAPIKey(HashAPIKey("key-c"))
+	t.Equal("c", k.ID)
+
+	s.Remove("a", "b")
+	k, err = s.LookupAPIKey(HashAPIKey("key-b"))
+	t.NoError(err)
+	t.Nil(k)
+	t.Len(s.Keys(), 1)
+}
+
+func (t *APIKeyTestSuite) TestFileKeyStore() {
+	dir, err := ioutil.TempDir("", "apikeys")
+	t.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "keys.json")
+	t.NoError(ioutil.WriteFile(path, []byte(`[{"id":"a","hash":"`+HashAPIKey("key-a")+`","subject":"partner"}]`), 0600))
+	s, err := NewFileKeyStore(path)
+	t.NoError(err)
+	k, _ := s.LookupAPIKey(HashAPIKey("key-a"))
+	t.Equal("partner", k.Subject)
+
+	t.NoError(ioutil.WriteFile(path, []byte(`[{"id":"b","hash":"`+HashAPIKey("key-b")+`"}]`), 0600))
+	t.NoError(s.Reload())
+	k, _ = s.LookupAPIKey(HashAPIKey("key-a"))
+	t.Nil(k)
+	k, _ = s.LookupAPIKey(HashAPIKey("key-b"))
+	t.Equal("b", k.ID)
+
+	// invalid files keep stored keys
+	t.NoError(ioutil.WriteFile(path, []byte(`[{"id":"c"}]`), 0600))
+	t.Error(s.Reload())
+	t.Len(s.Keys(), 1)
+
+	_, err = NewFileKeyStore(filepath.Join(dir, "missing.json"))
+	t.Error(err)
+}
+
+func (t *APIKeyTestSuite) TestServerAndClient() {
+	store := NewMemoryKeyStore(
+		&APIKey{ID: "a", Hash: HashAPIKey("key-a"), Subject: "partner", Metadata: map[string]string{"plan": "gold"}},
+		&APIKey{ID: "old", Hash: HashAPIKey("key-old"), Subject: "partner", ExpiresAt: time.Now().Add(-time.Second)},
+	)
+	a := NewAPIKeyAuth(store, APIKeyQuery("api_key"))
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).
+		Get("/me", func(ctx *Ctx) { ctx.OK(ctx.Subject() + ":" + ctx.APIKey().Metadata["plan"]) })
+	s.SetAuthFunc(a.AuthFunc())
+	go s.Listen()
+	defer ln.Close()
+	dial := func(string) (net.Conn, error) { return ln.Dial() }
+
+	cl := NewClient(ln.Addr().String()).SetDialFunc(dial).SetAuthFunc(APIKeyClientAuth("", "key-a"))
+	rs, err := cl.Get("/me")
+	t.NoError(err)
+	t.Equal(StatusOK, rs.StatusCode())
+	t.Equal(`"partner:gold"`, string(rs.Body()))
+
+	cl = NewClient(ln.Addr().String()).SetDialFunc(dial)
+	rs, err = cl.Get("/me?api_key=key-a")
+	t.NoError(err)
+	t.Equal(StatusOK, rs.StatusCode())
+
+	for _, key := range []string{"", "key-old", "unknown"} {
+		rs, err = cl.Request().SetURI("/me").SetHeader(DefaultAPIKeyHeader, key).Do()
+		t.NoError(err)
+		t.Equal(StatusUnauthorized, rs.StatusCode(), key)
+	}
+}
+
+type failingKeyStore struct{}
+
+func (failingKeyStore) LookupAPIKey(string) (*APIKey, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (t *APIKeyTestSuite) TestQueryRedactionAndStoreErrors() {
+	store := NewMemoryKeyStore(&APIKey{ID: "a", Hash: HashAPIKey("key-a"), Subject: "partner"})
+	exp := NewInMemoryExporter()
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).SetTracer(NewTracer("api", exp)).
+		SetAuthenticator(NewAPIKeyAuth(store, APIKeyQuery("api_key"))).
+		Get("/me", func(ctx *Ctx) { ctx.OK(ctx.Principal().Subject) })
+	go s.Listen()
+	defer ln.Close()
+	cl := NewClient(ln.Addr().String()).SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() })
+	rs, err := cl.Get("/me?x=1&api_key=key-a")
+	t.NoError(err)
+	t.Equal(StatusOK, rs.StatusCode())
+	spans := exp.Spans()
+	t.Len(spans, 1)
+	t.Equal("/me?x=1&api_key=REDACTED", spans[0].Attribute("http.target"))
+
+	// store errors are not authentication failures
+	ln = fasthttputil.NewInmemoryListener()
+	s = NewServer().SetListener(ln).SetAuthenticator(NewAPIKeyAuth(failingKeyStore{})).
+		Get("/me", func(ctx *Ctx) { ctx.OK("ok") })
+	go s.Listen()
+	defer ln.Close()
+	rs, err = NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }).
+		SetAuthFunc(APIKeyClientAuth("", "key-a")).
+		Get("/me")
+	t.NoError(err)
+	t.Equal(StatusServiceUnavailable, rs.StatusCode())
+	t.Equal(ErrAPIKeyStore.Error(), rs.Error().(*Error).Err)
+}
+
+func (t *APIKeyTestSuite) TestAuthenticate() {
+	store := NewMemoryKeyStore(&APIKey{ID: "a", Hash: HashAPIKey("key-a"), Subject: "partner", Scopes: []string{"read"}})
+	a := NewAPIKeyAuth(store, APIKeyHeader("X-Key"))
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).SetAuthenticator(a).
+		Get("/read", func(ctx *Ctx) { ctx.OK(ctx.Principal().Method) }, RequireScopes("read")).
+		Get("/write", func(ctx *Ctx) { ctx.OK("ok") }, RequireScopes("write"))
+	go s.Listen()
+	defer ln.Close()
+	cl := NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }).
+		SetAuthFunc(APIKeyClientAuth("X-Key", "key-a"))
+
+	rs, err := cl.Get("/read")
+	t.NoError(err)
+	t.Equal(`"apikey"`, string(rs.Body()))
+	rs, err = cl.Get("/write")
+	t.NoError(err)
+	t.Equal(StatusForbidden, rs.StatusCode())
+}
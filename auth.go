@@ -1,9 +1,164 @@
 package jsonapi
 
+import (
+	"errors"
+)
+
 // ClientAuthFunc is modifying the client request
 // to implement authentication in client
 type ClientAuthFunc func(*Request)
 
+// ClientSignFunc is modifying the client request right
+// before it is sent, for example: to sign the request body
+type ClientSignFunc func(*Request)
+
 // ServerAuthFunc is checking the server request for
 // authentication and returns a bool
 type ServerAuthFunc func(*Ctx) bool
+
+// Authenticator authenticates server requests. A returned *Error
+// is sent with its code (for example: 401 or 403) and message,
+// other errors are sent as 401 errors matching ErrUnauthorized.
+// A nil principal with a nil error allows anonymous requests
+type Authenticator interface {
+	Authenticate(*Ctx) (*Principal, error)
+}
+
+// AuthenticatorFunc is a func implementing Authenticator
+type AuthenticatorFunc func(*Ctx) (*Principal, error)
+
+// Authenticate implements Authenticator
+func (fn AuthenticatorFunc) Authenticate(ctx *Ctx) (*Principal, error) {
+	return fn(ctx)
+}
+
+// AuthFuncAuthenticator converts a ServerAuthFunc into an Authenticator,
+// the principal subject is the request subject set by fn
+func AuthFuncAuthenticator(fn ServerAuthFunc) Authenticator {
+	return AuthenticatorFunc(func(ctx *Ctx) (*Principal, error) {
+		if !fn(ctx) {
+			return nil, ErrUnauthorized
+		}
+		return &Principal{Subject: ctx.Subject()}, nil
+	})
+}
+
+// Principal is an authenticated caller
+type Principal struct {
+	Subject string
+	Method  string // authentication method, for example: jwt
+	Roles   []string
+	Scopes  []string
+	Claims  map[string]interface{}
+}
+
+// HasRole returns TRUE if the principal has role
+func (p *Principal) HasRole(role string) bool {
+	return contains(p.Roles, role)
+}
+
+// HasScope returns TRUE if the principal has scope
+func (p *Principal) HasScope(scope string) bool {
+	return contains(p.Scopes, scope)
+}
+
+// ChallengeError is an authentication error sent
+// with a WWW-Authenticate header
+type ChallengeError struct {
+	Err       error
+	Challenge string // WWW-Authenticate header value
+}
+
+// Error implements error interface
+func (e *ChallengeError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the authentication error
+func (e *ChallengeError) Unwrap() error {
+	return e.Err
+}
+
+// AuthChallenge returns err sent with a WWW-Authenticate challenge,
+// for example: AuthChallenge(err, `Bearer realm="api"`)
+func AuthChallenge(err error, challenge string) error {
+	return &ChallengeError{Err: err, Challenge: challenge}
+}
+
+const principalKey = "jsonapi.principal"
+
+// SetPrincipal sets the authenticated principal of the request
+// and its subject as the request subject
+func (c *Ctx) SetPrincipal(p *Principal) {
+	c.SetUserValue(principalKey, p)
+	c.SetSubject(p.Subject)
+}
+
+// Principal returns the authenticated principal of the request
+// or nil for anonymous requests
+func (c *Ctx) Principal() *Principal {
+	p, _ := c.UserValue(principalKey).(*Principal)
+	return p
+}
+
+// SetAuthenticator sets the authenticator of server requests,
+// nil disables authentication
+func (s *Server) SetAuthenticator(a Authenticator) *Server {
+	s.authenticator = a
+	s.hasAuth = a != nil
+	return s
+}
+
+// authenticate authenticates c or writes the authentication error
+func (s *Server) authenticate(c *Ctx) bool {
+	if s.authenticator == nil {
+		return true
+	}
+	p, err := s.authenticator.Authenticate(c)
+	if err != nil {
+		writeAuthError(c, err)
+		return false
+	}
+	if p != nil {
+		c.SetPrincipal(p)
+	}
+	return true
+}
+
+func writeAuthError(c *Ctx, err error) {
+	var ce *ChallengeError
+	if errors.As(err, &ce) {
+		c.SetHeader("WWW-Authenticate", ce.Challenge)
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		e = NewError(err, StatusUnauthorized).WithAppCode(ErrUnauthorized.AppCode)
+	}
+	if e.Code == 0 {
+		e = NewError(e, StatusUnauthorized)
+	}
+	c.Err(e, e.Code)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+const subjectKey = "jsonapi.subject"
+
+// SetSubject sets the authenticated subject (for example: user id)
+// of the request, it is recorded in access logs
+func (c *Ctx) SetSubject(subject string) {
+	c.SetUserValue(subjectKey, subject)
+}
+
+// Subject returns the authenticated subject of the request
+func (c *Ctx) Subject() string {
+	s, _ := c.UserValue(subjectKey).(string)
+	return s
+}
@@ -0,0 +1,124 @@
+package jsonapi
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestAuth(t *testing.T) {
+	suite.Run(t, new(AuthTestSuite))
+}
+
+type AuthTestSuite struct {
+	suite.Suite
+}
+
+func (t *AuthTestSuite) serve(a Authenticator) (*Client, func()) {
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).
+		SetAuthenticator(a).
+		Get("/me", func(ctx *Ctx) {
+			if p := ctx.Principal(); p != nil {
+				ctx.OK(p.Subject)
+				return
+			}
+			ctx.OK("anonymous")
+		})
+	go s.Listen()
+	return NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }), func() { ln.Close() }
+}
+
+func (t *AuthTestSuite) get(cl *Client, user string) *Response {
+	rs, err := cl.Request().SetMethod(MethodGet).SetURI("/me").SetHeader("X-User", user).Do()
+	t.NoError(err)
+	return rs
+}
+
+func (t *AuthTestSuite) TestAuthenticator() {
+	cl, closeFn := t.serve(AuthenticatorFunc(func(ctx *Ctx) (*Principal, error) {
+		switch ctx.GetHeader("X-User") {
+		case "":
+			return nil, nil
+		case "admin":
+			return &Principal{Subject: "admin", Roles: []string{"admin"}}, nil
+		case "banned":
+			return nil, NewErrorString("user is banned", StatusForbidden).WithAppCode("banned")
+		case "challenge":
+			return nil, AuthChallenge(errors.New("token required"), `Bearer realm="api"`)
+		}
+		return nil, errors.New("unknown user")
+	}))
+	defer closeFn()
+
+	rs := t.get(cl, "admin")
+	t.Equal(StatusOK, rs.StatusCode())
+	t.Equal(`"admin"`, string(rs.Body()))
+	t.Equal(`"anonymous"`, string(t.get(cl, "").Body()))
+
+	rs = t.get(cl, "banned")
+	t.Equal(StatusForbidden, rs.StatusCode())
+	e := rs.Error().(*Error)
+	t.Equal("user is banned", e.Err)
+	t.Equal("banned", e.AppCode)
+
+	rs = t.get(cl, "challenge")
+	t.Equal(StatusUnauthorized, rs.StatusCode())
+	t.Equal(`Bearer realm="api"`, string(rs.Header.Peek("WWW-Authenticate")))
+	t.Equal("token required", rs.Error().(*Error).Err)
+	t.True(errors.Is(rs.Error(), ErrUnauthorized))
+
+	rs = t.get(cl, "other")
+	t.Equal(StatusUnauthorized, rs.StatusCode())
+	t.Equal("unknown user", rs.Error().(*Error).Err)
+	t.Empty(rs.Header.Peek("WWW-Authenticate"))
+}
+
+func (t *AuthTestSuite) TestAuthFuncShim() {
+	cl, closeFn := t.serve(AuthFuncAuthenticator(func(ctx *Ctx) bool {
+		ctx.SetSubject(ctx.GetHeader("X-User"))
+		return ctx.GetHeader("X-User") != "guest"
+	}))
+	defer closeFn()
+	t.Equal(`"user-1"`, string(t.get(cl, "user-1").Body()))
+	rs := t.get(cl, "guest")
+	t.Equal(StatusUnauthorized, rs.StatusCode())
+	t.True(errors.Is(rs.Error(), ErrUnauthorized))
+}
+
+func (t *AuthTestSuite) TestPrincipal() {
+	p := &Principal{Roles: []string{"admin"}, Scopes: []string{"animals:read"}}
+	t.True(p.HasRole("admin"))
+	t.False(p.HasRole("user"))
+	t.True(p.HasScope("animals:read"))
+	t.False(p.HasScope("animals:write"))
+}
+
+func (t *AuthTestSuite) TestJWTAuthenticator() {
+	secret := []byte("secret")
+	a, err := NewJWTAuth(JWTKey("", secret))
+	t.NoError(err)
+	token, err := SignJWT(secret, "", Claims{"sub": "user-1", "scope": "animals:read animals:write", "roles": []string{"admin"}})
+	t.NoError(err)
+	ctx := &Ctx{&fasthttp.RequestCtx{}}
+	ctx.Request.Header.Set("Authorization", "Bearer "+token)
+	p, err := a.Authenticate(ctx)
+	t.NoError(err)
+	t.Equal("user-1", p.Subject)
+	t.Equal("jwt", p.Method)
+	t.Equal([]string{"animals:read", "animals:write"}, p.Scopes)
+	t.Equal([]string{"admin"}, p.Roles)
+	t.Equal("user-1", ctx.Claims().Subject())
+
+	ctx.Request.Header.Set("Authorization", "Bearer invalid")
+	_, err = a.Authenticate(ctx)
+	t.Equal(ErrInvalidToken, errors.Unwrap(err))
+	ce := new(ChallengeError)
+	t.True(errors.As(err, &ce))
+	t.Equal(`Bearer error="invalid_token"`, ce.Challenge)
+}
@@ -0,0 +1,114 @@
+package jsonapi
+
+import (
+	"strings"
+)
+
+// Policy authorizes an authenticated request, p is nil for anonymous
+// requests. A returned *Error is sent with its code, other errors
+// are sent as 403 errors
+type Policy func(ctx *Ctx, p *Principal) error
+
+// Public makes the route skip authentication and authorization
+func Public() RouteOption {
+	return func(r *route) {
+		r.public = true
+	}
+}
+
+// RequireScopes requires the principal to have all scopes
+func RequireScopes(scopes ...string) RouteOption {
+	return func(r *route) {
+		r.scopes = append(r.scopes, scopes...)
+	}
+}
+
+// RequireRoles requires the principal to have any of roles
+func RequireRoles(roles ...string) RouteOption {
+	return func(r *route) {
+		r.roles = append(r.roles, roles...)
+	}
+}
+
+// RequirePolicy adds a route policy evaluated after
+// scopes and roles of the route are checked
+func RequirePolicy(p Policy) RouteOption {
+	return func(r *route) {
+		r.policies = append(r.policies, p)
+	}
+}
+
+// SetPolicy sets a policy evaluated for every not public
+// route after the route requirements are checked
+func (s *Server) SetPolicy(p Policy) *Server {
+	s.policy = p
+	return s
+}
+
+// CRUDAccess are route options of crud read (Get, GetByID)
+// and write (Create, Update, Delete) operations
+type CRUDAccess struct {
+	Read  []RouteOption
+	Write []RouteOption
+}
+
+// CRUDAccessController is an optional interface of CRUDController
+// declaring options, for example permissions, of read and write routes
+type CRUDAccessController interface {
+	Access() CRUDAccess
+}
+
+// authorize checks route requirements and policies of c
+// or writes the authorization error
+func (s *Server) authorize(c *Ctx, rt *route) bool {
+	if len(rt.scopes) == 0 && len(rt.roles) == 0 && len(rt.policies) == 0 && s.policy == nil {
+		return true
+	}
+	p := c.Principal()
+	if p == nil && (len(rt.scopes) > 0 || len(rt.roles) > 0) {
+		writeAuthError(c, ErrUnauthorized)
+		return false
+	}
+	for _, scope := range rt.scopes {
+		if !p.HasScope(scope) {
+			c.Err(ErrForbidden.WithDetails(map[string]interface{}{"scope": scope}), StatusForbidden)
+			return false
+		}
+	}
+	if len(rt.roles) > 0 && !hasAnyRole(p, rt.roles) {
+		c.Err(ErrForbidden.WithDetails(map[string]interface{}{
+			"roles": strings.Join(rt.roles, ","),
+		}), StatusForbidden)
+		return false
+	}
+	for _, policy := range rt.policies {
+		if err := policy(c, p); err != nil {
+			writePolicyError(c, err)
+			return false
+		}
+	}
+	if s.policy != nil {
+		if err := s.policy(c, p); err != nil {
+			writePolicyError(c, err)
+			return false
+		}
+	}
+	return true
+}
+
+func hasAnyRole(p *Principal, roles []string) bool {
+	for _, role := range roles {
+		if p.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+func writePolicyError(c *Ctx, err error) {
+	e := c.server().mapError(err, StatusForbidden)
+	if e.Code == 0 {
+		e = NewError(e, StatusForbidden)
+	}
+	c.Err(e, e.Code)
+}
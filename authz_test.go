@@ -0,0 +1,190 @@
+package jsonapi
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestAuthz(t *testing.T) {
+	suite.Run(t, new(AuthzTestSuite))
+}
+
+type AuthzTestSuite struct {
+	suite.Suite
+}
+
+type accessCRUD struct {
+	apiCRUD
+}
+
+func (c *accessCRUD) Access() CRUDAccess {
+	return CRUDAccess{
+		Read:  []RouteOption{RequireScopes("animals:read")},
+		Write: []RouteOption{RequireScopes("animals:write")},
+	}
+}
+
+type adminController struct {
+	BaseController
+}
+
+func (c *adminController) Methods() ControllerMethods {
+	return ControllerMethods{
+		MethodGet:  {"/stats": c.stats},
+		MethodPost: {"/reset": c.stats},
+	}
+}
+
+func (c *adminController) RouteOptions() ControllerRouteOptions {
+	return ControllerRouteOptions{
+		MethodGet:  {"/stats": {RequireRoles("admin", "auditor")}},
+		MethodPost: {"/reset": {RequireRoles("admin")}},
+	}
+}
+
+func (c *adminController) stats(*Ctx) *Result { return c.OK("ok") }
+
+// principal authenticates "subject|role1,role2|scope1,scope2" X-User headers
+func principal(ctx *Ctx) (*Principal, error) {
+	h := ctx.GetHeader("X-User")
+	if h == "" {
+		return nil, nil
+	}
+	parts := strings.Split(h, "|")
+	if len(parts) != 3 {
+		return nil, errors.New("invalid user")
+	}
+	return &Principal{
+		Subject: parts[0],
+		Roles:   strings.Split(parts[1], ","),
+		Scopes:  strings.Split(parts[2], ","),
+	}, nil
+}
+
+func (t *AuthzTestSuite) serve() (*Server, *Client, func()) {
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).
+		SetAuthenticator(AuthenticatorFunc(principal)).
+		SetPolicy(func(ctx *Ctx, p *Principal) error {
+			if p != nil && p.Subject == "blocked" {
+				return NewErrorString("blocked", 423)
+			}
+			return nil
+		}).
+		Get("/health", func(ctx *Ctx) { ctx.OK("ok") }, Public()).
+		Get("/owned/:owner", func(ctx *Ctx) { ctx.OK("ok") }, RequirePolicy(func(ctx *Ctx, p *Principal) error {
+			if p == nil || p.Subject != ctx.GetParamString("owner") {
+				return errors.New("not an owner")
+			}
+			return nil
+		})).
+		CRUDController("/animals", new(accessCRUD)).
+		Controller("/admin", new(adminController))
+	go s.Listen()
+	return s, NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }), func() { ln.Close() }
+}
+
+func (t *AuthzTestSuite) TestAuthorize() {
+	_, cl, closeFn := t.serve()
+	defer closeFn()
+	tests := []struct {
+		method string
+		uri    string
+		user   string
+		code   int
+	}{
+		{MethodGet, "/health", "", StatusOK},
+		{MethodGet, "/health", "invalid", StatusOK},
+		{MethodGet, "/animals", "", StatusUnauthorized},
+		{MethodGet, "/animals", "u|user|animals:read", StatusOK},
+		{MethodGet, "/animals/1", "u|user|animals:read", StatusOK},
+		{MethodPost, "/animals", "u|user|animals:read", StatusForbidden},
+		{MethodDelete, "/animals/1", "u|user|animals:read,animals:write", StatusOK},
+		{MethodGet, "/admin/stats", "u|auditor|", StatusOK},
+		{MethodPost, "/admin/reset", "u|auditor|", StatusForbidden},
+		{MethodPost, "/admin/reset", "u|admin|", StatusOK},
+		{MethodGet, "/admin/stats", "blocked|admin|", 423},
+		{MethodGet, "/owned/u", "u||", StatusOK},
+		{MethodGet, "/owned/u", "other||", StatusForbidden},
+	}
+	for _, tt := range tests {
+		rs, err := cl.Request().SetMethod(tt.method).SetURI(tt.uri).SetHeader("X-User", tt.user).Do()
+		t.NoError(err)
+		t.Equal(tt.code, rs.StatusCode(), tt.method+" "+tt.uri+" "+tt.user)
+		if tt.code == StatusForbidden {
+			t.True(errors.Is(rs.Error(), ErrForbidden) || rs.Error().(*Error).Err == "not an owner")
+		}
+	}
+}
+
+func (t *AuthzTestSuite) TestRouteInfo() {
+	s, _, closeFn := t.serve()
+	defer closeFn()
+	info := map[string]RouteInfo{}
+	for _, r := range s.Routes() {
+		info[r.Method+" "+r.Path] = r
+	}
+	t.False(info["GET /health"].AuthRequired)
+	t.True(info["GET /animals"].AuthRequired)
+	t.Equal([]string{"animals:write"}, info["PUT /animals/:id"].Scopes)
+	t.Equal([]string{"admin", "auditor"}, info["GET /admin/stats"].Roles)
+
+	doc := s.OpenAPI(OpenAPIInfo{Security: []string{"oauth"}})
+	t.Empty(doc.Paths["/health"]["get"].Security)
+	t.Nil(doc.Paths["/health"]["get"].Responses["401"])
+	t.Equal([]map[string][]string{{"oauth": {"animals:read"}}}, doc.Paths["/animals"]["get"].Security)
+}
+
+func (t *AuthzTestSuite) TestServedRoutes() {
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).
+		SetAuthenticator(AuthenticatorFunc(principal)).
+		ServeMetrics("/metrics", Public()).
+		ServeOpenAPI("/openapi.json", OpenAPIInfo{}, Public()).
+		ServeRoutes("/routes", RequireRoles("admin")).
+		SSE("/events", func(*Ctx, *EventStream) error { return nil }, RequireScopes("events")).
+		WebSocket("/ws", func(*WSConn) error { return nil }, RequireScopes("ws")).
+		JSONRPC("/rpc", NewRPCRegistry(), RequireRoles("rpc")).
+		Batch("/batch", BatchRouteOptions(RequireScopes("batch")))
+	go s.Listen()
+	defer ln.Close()
+	cl := NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() })
+	tests := []struct {
+		method string
+		uri    string
+		user   string
+		code   int
+	}{
+		{MethodGet, "/metrics", "", StatusOK},
+		{MethodGet, "/openapi.json", "", StatusOK},
+		{MethodGet, "/routes", "", StatusUnauthorized},
+		{MethodGet, "/routes", "u|user|", StatusForbidden},
+		{MethodGet, "/routes", "u|admin|", StatusOK},
+		{MethodGet, "/events", "", StatusUnauthorized},
+		{MethodGet, "/events", "u||other", StatusForbidden},
+		{MethodGet, "/ws", "u||other", StatusForbidden},
+		{MethodPost, "/rpc", "", StatusUnauthorized},
+		{MethodPost, "/rpc", "u|user|", StatusForbidden},
+		{MethodPost, "/batch", "u||other", StatusForbidden},
+		{MethodPost, "/batch", "u||batch", StatusOK},
+	}
+	for _, tt := range tests {
+		rs, err := cl.Request().SetMethod(tt.method).SetURI(tt.uri).SetHeader("X-User", tt.user).SetBody([]byte(`[]`)).Do()
+		t.NoError(err)
+		t.Equal(tt.code, rs.StatusCode(), tt.method+" "+tt.uri+" "+tt.user)
+	}
+	info := map[string]RouteInfo{}
+	for _, r := range s.Routes() {
+		info[r.Method+" "+r.Path] = r
+	}
+	t.False(info["GET /metrics"].AuthRequired)
+	t.Equal([]string{"events"}, info["GET /events"].Scopes)
+	t.Equal([]string{"batch"}, info["POST /batch"].Scopes)
+}
@@ -0,0 +1,266 @@
+package jsonapi
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	basicUserKey = "jsonapi.basicuser"
+)
+
+var (
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrUnsupportedPassHash = errors.New("unsupported password hash")
+	ErrUserStore           = errors.New("user store is unavailable")
+)
+
+// User is a stored user of BasicAuth, Hash is a bcrypt
+// or argon2id (PHC string format) password hash
+type User struct {
+	Name  string
+	Hash  string
+	Roles []string
+}
+
+// UserStore looks up users by name, a nil user
+// with a nil error is returned for unknown users
+type UserStore interface {
+	LookupUser(name string) (*User, error)
+}
+
+// NewMemoryUserStore creates an in-memory UserStore of users
+func NewMemoryUserStore(users ...*User) *MemoryUserStore {
+	s := &MemoryUserStore{users: map[string]*User{}, mu: new(sync.RWMutex)}
+	s.Add(users...)
+	return s
+}
+
+// MemoryUserStore is an in-memory UserStore
+type MemoryUserStore struct {
+	users map[string]*User
+	mu    *sync.RWMutex
+}
+
+// LookupUser implements UserStore
+func (s *MemoryUserStore) LookupUser(name string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.users[name], nil
+}
+
+// Add adds users replacing users with the same name
+func (s *MemoryUserStore) Add(users ...*User) {
+	s.mu.Lock()
+	for _, u := range users {
+		s.users[u.Name] = u
+	}
+	s.mu.Unlock()
+}
+
+// Remove removes users by name
+func (s *MemoryUserStore) Remove(names ...string) {
+	s.mu.Lock()
+	for _, name := range names {
+		delete(s.users, name)
+	}
+	s.mu.Unlock()
+}
+
+// HashPassword returns the bcrypt hash of password
+func HashPassword(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(b), err
+}
+
+// argon2id parameters of HashPasswordArgon2
+const (
+	argon2Time    = 1
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	argon2KeyLen  = 32
+
+	// argon2MaxMemory limits the memory of stored hashes in KiB
+	argon2MaxMemory = 1024 * 1024
+)
+
+// HashPasswordArgon2 returns the argon2id hash of password in PHC
+// string format: $argon2id$v=19$m=65536,t=1,p=4$salt$hash
+func HashPasswordArgon2(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// VerifyPassword returns nil if password matches a bcrypt or argon2id
+// hash, ErrInvalidCredentials if not and ErrUnsupportedPassHash
+// for other hashes
+func VerifyPassword(hash string, password string) error {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return ErrInvalidCredentials
+		}
+		return err
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return verifyArgon2(hash, password)
+	}
+	return ErrUnsupportedPassHash
+}
+
+func verifyArgon2(hash string, password string) error {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return ErrUnsupportedPassHash
+	}
+	var version int
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return ErrUnsupportedPassHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil ||
+		iterations == 0 || threads == 0 || memory > argon2MaxMemory {
+		return ErrUnsupportedPassHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return ErrUnsupportedPassHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return ErrUnsupportedPassHash
+	}
+	other := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+var (
+	dummyHash []byte
+	dummyOnce sync.Once
+)
+
+// verifyDummy verifies a dummy hash for unknown users,
+// so they take as long to reject as wrong passwords
+func verifyDummy(password string) {
+	dummyOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy"), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+// NewBasicAuth creates a HTTP Basic authenticator of users of store,
+// realm is sent in WWW-Authenticate challenges. Digest authentication
+// can't be verified with bcrypt or argon2 hashes, see NewDigestAuth
+func NewBasicAuth(realm string, store UserStore) *BasicAuth {
+	return &BasicAuth{
+		store:     store,
+		challenge: `Basic realm="` + strings.Replace(realm, `"`, `\"`, -1) + `", charset="UTF-8"`,
+	}
+}
+
+// BasicAuth verifies HTTP Basic credentials
+type BasicAuth struct {
+	store     UserStore
+	challenge string
+}
+
+// Authenticate implements Authenticator
+func (a *BasicAuth) Authenticate(ctx *Ctx) (*Principal, error) {
+	name, password, ok := BasicCredentials(ctx)
+	if !ok {
+		return nil, AuthChallenge(ErrUnauthorized, a.challenge)
+	}
+	u, err := a.store.LookupUser(name)
+	if err != nil {
+		return nil, userStoreError(err)
+	}
+	if u == nil {
+		verifyDummy(password)
+		return nil, AuthChallenge(ErrInvalidCredentials, a.challenge)
+	}
+	if err := VerifyPassword(u.Hash, password); err != nil {
+		if err == ErrInvalidCredentials {
+			return nil, AuthChallenge(err, a.challenge)
+		}
+		return nil, userStoreError(err)
+	}
+	ctx.SetUserValue(basicUserKey, u)
+	return &Principal{Subject: u.Name, Method: "basic", Roles: u.Roles}, nil
+}
+
+// userStoreError hides errors of user stores and stored hashes from the
+// client: store errors are StatusServiceUnavailable, hashes that can't
+// be verified are internal errors. err is kept as the cause
+func userStoreError(err error) error {
+	e := NewError(ErrUserStore, StatusServiceUnavailable)
+	if err == ErrUnsupportedPassHash {
+		e = NewError(ErrInternal, StatusInternalServerError)
+	}
+	e.cause = err
+	return e
+}
+
+// AuthFunc returns a ServerAuthFunc accepting requests with valid
+// credentials, it sets the user name as the request subject and the
+// WWW-Authenticate challenge of rejected requests
+func (a *BasicAuth) AuthFunc() ServerAuthFunc {
+	return func(ctx *Ctx) bool {
+		p, err := a.Authenticate(ctx)
+		if err != nil {
+			ctx.SetHeader("WWW-Authenticate", a.challenge)
+			return false
+		}
+		ctx.SetSubject(p.Subject)
+		return true
+	}
+}
+
+// User returns the user of a request verified by BasicAuth or nil
+func (c *Ctx) User() *User {
+	u, _ := c.UserValue(basicUserKey).(*User)
+	return u
+}
+
+// BasicCredentials returns credentials of the Basic Authorization header
+func BasicCredentials(ctx *Ctx) (name string, password string, ok bool) {
+	auth := ctx.GetHeader("Authorization")
+	if len(auth) < 6 || !strings.EqualFold(auth[:6], "Basic ") {
+		return "", "", false
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(auth[6:]))
+	if err != nil {
+		return "", "", false
+	}
+	i := strings.IndexByte(string(b), ':')
+	if i < 0 {
+		return "", "", false
+	}
+	return string(b[:i]), string(b[i+1:]), true
+}
+
+// BasicClientAuth returns a ClientAuthFunc sending Basic credentials
+func BasicClientAuth(name string, password string) ClientAuthFunc {
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(name+":"+password))
+	return func(r *Request) {
+		r.SetHeader("Authorization", auth)
+	}
+}
@@ -0,0 +1,154 @@
+package jsonapi
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestBasicAuth(t *testing.T) {
+	suite.Run(t, new(BasicAuthTestSuite))
+}
+
+type BasicAuthTestSuite struct {
+	suite.Suite
+}
+
+func (t *BasicAuthTestSuite) TestVerifyPassword() {
+	for _, hash := range []func(string) (string, error){HashPassword, HashPasswordArgon2} {
+		h, err := hash("secret")
+		t.NoError(err)
+		t.NoError(VerifyPassword(h, "secret"), h)
+		t.Equal(ErrInvalidCredentials, VerifyPassword(h, "other"), h)
+	}
+	t.Equal(ErrUnsupportedPassHash, VerifyPassword("plain", "plain"))
+	for _, h := range []string{
+		"$argon2id$v=19$m=1,t=1,p=1$!$!",
+		"$argon2id$v=19$m=8,t=1,p=1$c2FsdA$",
+		"$argon2id$v=19$m=8,t=0,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=8,t=1,p=0$c2FsdA$a2V5",
+		"$argon2id$v=19$m=4294967295,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=18$m=8,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=8,t=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=8,t=1,p=1$c2FsdA",
+	} {
+		t.Equal(ErrUnsupportedPassHash, VerifyPassword(h, "x"), h)
+	}
+}
+
+type failingUserStore struct{}
+
+func (failingUserStore) LookupUser(string) (*User, error) {
+	return nil, errors.New("db is down")
+}
+
+func (t *BasicAuthTestSuite) TestStoreErrors() {
+	store := NewMemoryUserStore(&User{Name: "alice", Hash: "plain"})
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).
+		SetRateLimiter(NewRateLimiter(TokenBucket(1, time.Hour))).
+		Get("/basic", func(ctx *Ctx) { ctx.OK("ok") }).
+		Get("/digest", func(ctx *Ctx) { ctx.OK("ok") })
+	auths := map[string]Authenticator{
+		"/basic":  NewBasicAuth("internal", failingUserStore{}),
+		"/digest": NewDigestAuth("internal", failingUserStore{}),
+	}
+	s.SetAuthenticator(AuthenticatorFunc(func(ctx *Ctx) (*Principal, error) {
+		if ctx.QueryArgs().Has("hash") {
+			return NewBasicAuth("internal", store).Authenticate(ctx)
+		}
+		return auths[string(ctx.Path())].Authenticate(ctx)
+	}))
+	go s.Listen()
+	defer ln.Close()
+	cl := NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }).
+		SetAuthFunc(BasicClientAuth("alice", "pass"))
+
+	// store errors are hidden and don't count as rejected credentials
+	for i := 0; i < 3; i++ {
+		rs, err := cl.Get("/basic")
+		t.NoError(err)
+		t.Equal(StatusServiceUnavailable, rs.StatusCode())
+		t.Equal(ErrUserStore.Error(), rs.Error().(*Error).Err)
+		rs, err = cl.Get("/basic?hash")
+		t.NoError(err)
+		t.Equal(StatusInternalServerError, rs.StatusCode())
+		t.Equal(ErrInternal.Error(), rs.Error().(*Error).Err)
+	}
+	rs, err := NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }).
+		SetDigestAuth("alice", "pass").
+		Get("/digest")
+	t.NoError(err)
+	t.Equal(StatusServiceUnavailable, rs.StatusCode())
+	t.Equal(ErrUserStore.Error(), rs.Error().(*Error).Err)
+}
+
+func (t *BasicAuthTestSuite) TestServerAndClient() {
+	bcryptHash, err := HashPassword("bcrypt-pass")
+	t.NoError(err)
+	argonHash, err := HashPasswordArgon2("argon-pass")
+	t.NoError(err)
+	store := NewMemoryUserStore(
+		&User{Name: "alice", Hash: bcryptHash, Roles: []string{"admin"}},
+		&User{Name: "bob", Hash: argonHash},
+	)
+	a := NewBasicAuth("internal", store)
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).
+		Get("/me", func(ctx *Ctx) { ctx.OK(ctx.Subject() + ":" + ctx.User().Name) })
+	s.SetAuthFunc(a.AuthFunc())
+	go s.Listen()
+	defer ln.Close()
+	client := func(auth ClientAuthFunc) *Client {
+		cl := NewClient(ln.Addr().String()).SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() })
+		if auth != nil {
+			cl.SetAuthFunc(auth)
+		}
+		return cl
+	}
+
+	rs, err := client(BasicClientAuth("alice", "bcrypt-pass")).Get("/me")
+	t.NoError(err)
+	t.Equal(StatusOK, rs.StatusCode())
+	t.Equal(`"alice:alice"`, string(rs.Body()))
+	rs, err = client(BasicClientAuth("bob", "argon-pass")).Get("/me")
+	t.NoError(err)
+	t.Equal(StatusOK, rs.StatusCode())
+
+	for _, auth := range []ClientAuthFunc{nil, BasicClientAuth("alice", "wrong"), BasicClientAuth("carol", "x")} {
+		rs, err = client(auth).Get("/me")
+		t.NoError(err)
+		t.Equal(StatusUnauthorized, rs.StatusCode())
+		t.Equal(`Basic realm="internal", charset="UTF-8"`, string(rs.Header.Peek("WWW-Authenticate")))
+		t.Equal(ErrUnauthorized.AppCode, rs.Error().(*Error).AppCode)
+	}
+}
+
+func (t *BasicAuthTestSuite) TestAuthenticator() {
+	hash, err := HashPassword("pass")
+	t.NoError(err)
+	store := NewMemoryUserStore(&User{Name: "alice", Hash: hash, Roles: []string{"admin"}})
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).SetAuthenticator(NewBasicAuth("internal", store)).
+		Get("/admin", func(ctx *Ctx) { ctx.OK(ctx.Principal().Method) }, RequireRoles("admin"))
+	go s.Listen()
+	defer ln.Close()
+	cl := NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }).
+		SetAuthFunc(BasicClientAuth("alice", "pass"))
+	rs, err := cl.Get("/admin")
+	t.NoError(err)
+	t.Equal(`"basic"`, string(rs.Body()))
+
+	store.Remove("alice")
+	rs, err = cl.Get("/admin")
+	t.NoError(err)
+	t.Equal(StatusUnauthorized, rs.StatusCode())
+	t.Equal(ErrInvalidCredentials.Error(), rs.Error().(*Error).Err)
+}
@@ -0,0 +1,254 @@
+package jsonapi
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/mailru/easyjson"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// DefaultBatchMaxOperations is the default maximum
+	// number of operations in a single batch
+	DefaultBatchMaxOperations = 50
+
+	batchKey = "jsonapi.batch"
+)
+
+var (
+	ErrBatchTooLarge    = errors.New("too many batch operations")
+	ErrBatchNested      = errors.New("nested batch requests are not allowed")
+	ErrBatchUnsupported = errors.New("route can't be called in a batch")
+	ErrBatchNotExecuted = errors.New("not executed, previous operation failed")
+)
+
+// BatchOperation is a single request of a batch
+//
+//easyjson:json
+type BatchOperation struct {
+	Method  string              `json:"method"`
+	Path    string              `json:"path"`
+	Headers map[string]string   `json:"headers,omitempty"`
+	Body    easyjson.RawMessage `json:"body,omitempty"`
+}
+
+// BatchOperations is a list of batch operations
+//
+//easyjson:json
+type BatchOperations []*BatchOperation
+
+// BatchResult is a response to a single batch operation
+//
+//easyjson:json
+type BatchResult struct {
+	Status  int                 `json:"status"`
+	Headers map[string]string   `json:"headers,omitempty"`
+	Body    easyjson.RawMessage `json:"body,omitempty"`
+}
+
+// BatchResults is a list of batch results, in the
+// order of batch operations
+//
+//easyjson:json
+type BatchResults []*BatchResult
+
+// BatchOption configures a batch endpoint
+type BatchOption func(*batchConfig)
+
+// BatchSequential executes operations one by one in order,
+// by default operations are executed concurrently
+func BatchSequential() BatchOption {
+	return func(c *batchConfig) {
+		c.sequential = true
+	}
+}
+
+// BatchAtomic executes operations one by one and stops at the first
+// failed (status >= 400) operation. Remaining operations are not executed
+// and get StatusFailedDependency. Already executed operations are not
+// rolled back
+func BatchAtomic() BatchOption {
+	return func(c *batchConfig) {
+		c.sequential = true
+		c.atomic = true
+	}
+}
+
+// BatchMaxOperations sets the maximum number of operations in a batch
+func BatchMaxOperations(n int) BatchOption {
+	return func(c *batchConfig) {
+		c.maxOperations = n
+	}
+}
+
+// BatchMaxBodySize sets the maximum batch request body size in bytes
+func BatchMaxBodySize(n int) BatchOption {
+	return func(c *batchConfig) {
+		c.maxBodySize = n
+	}
+}
+
+// BatchRouteOptions sets options of the batch route,
+// for example: Public or RequireScopes
+func BatchRouteOptions(opts ...RouteOption) BatchOption {
+	return func(c *batchConfig) {
+		c.routeOptions = append(c.routeOptions, opts...)
+	}
+}
+
+type batchConfig struct {
+	path          string
+	sequential    bool
+	atomic        bool
+	maxOperations int
+	maxBodySize   int
+	routeOptions  []RouteOption
+}
+
+// Batch registers a batch endpoint on path. It accepts a json array of
+// BatchOperation, dispatches every operation through the server router
+// (including auth func) and returns a json array of BatchResult.
+// Operations inherit headers and the request id of the batch request,
+// headers of an operation override them. Operations of SSE, WebSocket,
+// JSONRPC and Batch routes and of other streamed or upgraded responses
+// get StatusBadRequest
+func (s *Server) Batch(p string, opts ...BatchOption) *Server {
+	cfg := &batchConfig{
+		path:          p,
+		maxOperations: DefaultBatchMaxOperations,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return s.Post(p, func(ctx *Ctx) {
+		if cfg.maxBodySize > 0 && len(ctx.PostBody()) > cfg.maxBodySize {
+			ctx.Err(errors.New("batch request is too large"), StatusRequestEntityTooLarge)
+			return
+		}
+		var ops BatchOperations
+		if err := ctx.ReadJSON(&ops); err != nil {
+			ctx.ErrBadRequest(err)
+			return
+		}
+		if cfg.maxOperations > 0 && len(ops) > cfg.maxOperations {
+			ctx.ErrBadRequest(ErrBatchTooLarge)
+			return
+		}
+		uri := fasthttp.AcquireURI()
+		defer fasthttp.ReleaseURI(uri)
+		for _, op := range ops {
+			if op == nil || op.Path == "" || op.Path[0] != '/' {
+				ctx.ErrBadRequest(errors.New("invalid batch operation path"))
+				return
+			}
+			uri.SetPath(strings.SplitN(op.Path, "?", 2)[0])
+			if string(uri.Path()) == cfg.path {
+				ctx.ErrBadRequest(ErrBatchNested)
+				return
+			}
+		}
+		ctx.OK(s.executeBatch(ctx, ops, cfg))
+	}, append([]RouteOption{noBatch(ErrBatchNested)}, cfg.routeOptions...)...)
+}
+
+// noBatch rejects batch operations calling the route with err,
+// for example: routes streaming or upgrading the response
+func noBatch(err error) RouteOption {
+	return func(r *route) {
+		r.batchErr = err
+	}
+}
+
+// rejectBatch writes the batch error of rt if c is a batch operation
+func rejectBatch(c *Ctx, rt *route) bool {
+	if rt.batchErr == nil || c.UserValue(batchKey) == nil {
+		return false
+	}
+	c.ErrBadRequest(rt.batchErr)
+	return true
+}
+
+func (s *Server) executeBatch(ctx *Ctx, ops BatchOperations, cfg *batchConfig) BatchResults {
+	res := make(BatchResults, len(ops))
+	// the logger of ctx is created on first use
+	logger := ctx.Logger()
+	if cfg.sequential {
+		for i, op := range ops {
+			res[i] = s.executeBatchOperation(ctx, logger, op)
+			if cfg.atomic && res[i].Status >= StatusBadRequest {
+				for j := i + 1; j < len(ops); j++ {
+					res[j] = batchError(ErrBatchNotExecuted, StatusFailedDependency)
+				}
+				break
+			}
+		}
+		return res
+	}
+	wg := new(sync.WaitGroup)
+	for i, op := range ops {
+		wg.Add(1)
+		go func(i int, op *BatchOperation) {
+			defer wg.Done()
+			res[i] = s.executeBatchOperation(ctx, logger, op)
+		}(i, op)
+	}
+	wg.Wait()
+	return res
+}
+
+func (s *Server) executeBatchOperation(ctx *Ctx, logger fasthttp.Logger, op *BatchOperation) *BatchResult {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	ctx.Request.Header.CopyTo(&req.Header)
+	// operations share the request id of the batch
+	req.Header.Set(s.requestIDHeader, ctx.RequestID())
+	method := op.Method
+	if method == "" {
+		method = MethodGet
+	}
+	req.Header.SetMethod(method)
+	req.SetRequestURI(op.Path)
+	req.SetBody(op.Body)
+	for k, v := range op.Headers {
+		req.Header.Set(k, v)
+	}
+	sub := new(fasthttp.RequestCtx)
+	sub.Init2(newDetachedConn(ctx), logger, true)
+	req.CopyTo(&sub.Request)
+	sub.SetUserValue(batchKey, true)
+	s.router.Handler(sub)
+	// streams and upgrades of routes not marked by noBatch never end
+	if sub.Response.IsBodyStream() || sub.Response.StatusCode() == fasthttp.StatusSwitchingProtocols {
+		sub.Response.CloseBodyStream()
+		return batchError(ErrBatchUnsupported, StatusBadRequest)
+	}
+	res := &BatchResult{
+		Status:  sub.Response.StatusCode(),
+		Headers: map[string]string{},
+	}
+	sub.Response.Header.VisitAll(func(k, v []byte) {
+		res.Headers[string(k)] = string(v)
+	})
+	body := sub.Response.Body()
+	if len(body) > 0 {
+		if bytes.HasPrefix(sub.Response.Header.ContentType(), []byte("application/json")) {
+			res.Body = append(easyjson.RawMessage{}, body...)
+		} else {
+			// non json responses are returned as json string
+			res.Body, _ = GetEncoder().Marshal(string(body))
+		}
+	}
+	return res
+}
+
+func batchError(err error, code int) *BatchResult {
+	b, _ := NewError(err, code).MarshalJSON()
+	return &BatchResult{
+		Status:  code,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    b,
+	}
+}
@@ -0,0 +1,191 @@
+package jsonapi
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestBatch(t *testing.T) {
+	suite.Run(t, new(BatchTestSuite))
+}
+
+type BatchTestSuite struct {
+	suite.Suite
+}
+
+func (t *BatchTestSuite) serve(opts ...BatchOption) (*fasthttputil.InmemoryListener, *Client) {
+	ln, s := new(ServerTestSuite).getServer()
+	s.SetAuthFunc(func(ctx *Ctx) bool {
+		return ctx.GetHeader("Authorization") == "secret"
+	})
+	s.Get("/animals/:id", func(ctx *Ctx) {
+		if ctx.GetParamString("id") == "0" {
+			ctx.ErrNotFound(errors.New("animal not found"))
+			return
+		}
+		ctx.OK(plainStruct{Name: ctx.GetParamString("id")})
+	})
+	s.Post("/animals", func(ctx *Ctx) {
+		p := new(plainStruct)
+		if err := ctx.ReadJSON(p); err != nil {
+			ctx.ErrBadRequest(err)
+			return
+		}
+		p.ID = 10
+		ctx.OK(p)
+	})
+	s.Get("/text", func(ctx *Ctx) {
+		ctx.SetHeader("Content-Type", "text/plain")
+		ctx.WriteString("plain text")
+	})
+	s.Get("/stream", func(ctx *Ctx) {
+		ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+			for {
+				if _, err := w.WriteString("data\n"); err != nil || w.Flush() != nil {
+					return
+				}
+			}
+		})
+	})
+	s.SSE("/events", func(ctx *Ctx, es *EventStream) error {
+		<-es.Done()
+		return nil
+	})
+	s.WebSocket("/ws", func(c *WSConn) error { return nil })
+	s.JSONRPC("/rpc", NewRPCRegistry())
+	s.Batch("/batch", opts...)
+	s.Batch("/other/batch")
+	go s.Listen()
+	return ln, NewClient("batch.local").
+		SetAuthFunc(func(r *Request) {
+			r.SetHeader("Authorization", "secret")
+		}).
+		SetDialFunc(func(string) (net.Conn, error) {
+			return ln.Dial()
+		})
+}
+
+func (t *BatchTestSuite) batch(c *Client, ops BatchOperations) (int, BatchResults) {
+	b, err := Marshal(ops)
+	t.NoError(err)
+	rs, err := c.Request().SetMethod(MethodPost).SetURI("/batch").
+		SetHeader(DefaultRequestIDHeader, "batch-1").SetBody(b).Do()
+	t.NoError(err)
+	var res BatchResults
+	if rs.StatusCode() == StatusOK {
+		t.NoError(rs.ReadJSON(&res))
+	}
+	return rs.StatusCode(), res
+}
+
+func (t *BatchTestSuite) TestBatch() {
+	ln, c := t.serve()
+	defer ln.Close()
+	code, res := t.batch(c, BatchOperations{
+		{Path: "/animals/1"},
+		{Method: MethodPost, Path: "/animals", Body: []byte(`{"name":"Tom"}`)},
+		{Path: "/animals/0"},
+		{Path: "/animals/2", Headers: map[string]string{"Authorization": "wrong"}},
+		{Path: "/text"},
+		{Path: "/missing"},
+	})
+	t.Equal(StatusOK, code)
+	t.Len(res, 6)
+	t.Equal(StatusOK, res[0].Status)
+	t.Equal(`{"id":0,"name":"1"}`, string(res[0].Body))
+	t.Equal("application/json", res[0].Headers["Content-Type"])
+	t.Equal(StatusOK, res[1].Status)
+	t.Equal(`{"id":10,"name":"Tom"}`, string(res[1].Body))
+	t.Equal(StatusNotFound, res[2].Status)
+	t.Equal(`{"error":"animal not found","code":404,"requestId":"batch-1"}`, string(res[2].Body))
+	t.Equal("batch-1", res[2].Headers["X-Request-Id"])
+	t.Equal(StatusUnauthorized, res[3].Status)
+	t.Equal(`"plain text"`, string(res[4].Body))
+	t.Equal(StatusNotFound, res[5].Status)
+}
+
+func (t *BatchTestSuite) TestAtomic() {
+	ln, c := t.serve(BatchAtomic())
+	defer ln.Close()
+	_, res := t.batch(c, BatchOperations{
+		{Path: "/animals/1"},
+		{Path: "/animals/0"},
+		{Path: "/animals/2"},
+	})
+	t.Len(res, 3)
+	t.Equal(StatusOK, res[0].Status)
+	t.Equal(StatusNotFound, res[1].Status)
+	t.Equal(StatusFailedDependency, res[2].Status)
+	t.Equal(`{"error":"not executed, previous operation failed","code":424}`, string(res[2].Body))
+}
+
+func (t *BatchTestSuite) TestSequential() {
+	ln, c := t.serve(BatchSequential())
+	defer ln.Close()
+	_, res := t.batch(c, BatchOperations{
+		{Path: "/animals/0"},
+		{Path: "/animals/1"},
+	})
+	t.Equal(StatusNotFound, res[0].Status)
+	t.Equal(StatusOK, res[1].Status)
+}
+
+func (t *BatchTestSuite) TestLimits() {
+	ln, c := t.serve(BatchMaxOperations(2), BatchMaxBodySize(100))
+	defer ln.Close()
+	code, _ := t.batch(c, BatchOperations{{Path: "/a"}, {Path: "/b"}, {Path: "/c"}})
+	t.Equal(StatusBadRequest, code)
+	code, _ = t.batch(c, BatchOperations{{Path: "/" + strings.Repeat("a", 100)}})
+	t.Equal(StatusRequestEntityTooLarge, code)
+	for _, p := range []string{"/batch", "/batch?a=1", "//batch", "/%62atch", "/a/../batch"} {
+		code, _ = t.batch(c, BatchOperations{{Method: MethodPost, Path: p}})
+		t.Equal(StatusBadRequest, code, p)
+	}
+	code, _ = t.batch(c, BatchOperations{{Path: "animals"}})
+	t.Equal(StatusBadRequest, code)
+	rs, err := c.Post("/batch", BytesResult(`{`))
+	t.NoError(err)
+	t.Equal(StatusBadRequest, rs.StatusCode())
+}
+
+func (t *BatchTestSuite) TestNested() {
+	ln, c := t.serve()
+	defer ln.Close()
+	code, res := t.batch(c, BatchOperations{
+		{Method: MethodPost, Path: "/other/batch", Body: []byte(`[{"path":"/animals/1"}]`)},
+	})
+	t.Equal(StatusOK, code)
+	t.Len(res, 1)
+	t.Equal(StatusBadRequest, res[0].Status)
+	t.Contains(string(res[0].Body), ErrBatchNested.Error())
+}
+
+func (t *BatchTestSuite) TestUnsupported() {
+	ln, c := t.serve()
+	defer ln.Close()
+	code, res := t.batch(c, BatchOperations{
+		{Path: "/events"},
+		{Path: "/ws", Headers: map[string]string{"Connection": "Upgrade", "Upgrade": "websocket"}},
+		{Method: MethodPost, Path: "/rpc", Body: []byte(`{"jsonrpc":"2.0","method":"a","id":1}`)},
+		{Path: "/stream"},
+	})
+	t.Equal(StatusOK, code)
+	t.Len(res, 4)
+	for _, r := range res {
+		t.Equal(StatusBadRequest, r.Status)
+		t.Contains(string(r.Body), ErrBatchUnsupported.Error())
+	}
+}
+
+func (t *BatchTestSuite) TestUnauthorized() {
+	ln, c := t.serve()
+	defer ln.Close()
+	code, _ := t.batch(c.SetAuthFunc(func(*Request) {}), BatchOperations{{Path: "/animals/1"}})
+	t.Equal(StatusUnauthorized, code)
+}
@@ -0,0 +1,238 @@
+package jsonapi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCertReloadInterval is the default interval of
+// certificate file modification checks
+const DefaultCertReloadInterval = time.Minute
+
+// NewCertReloader creates a CertReloader of the certFile, keyFile pair,
+// more pairs are added with AddCert. Certificates are loaded by Reload
+// or the first check of Watch
+func NewCertReloader(certFile string, keyFile string) *CertReloader {
+	r := &CertReloader{
+		mu:      new(sync.RWMutex),
+		names:   map[string]*tls.Certificate{},
+		onError: func(error) {},
+	}
+	return r.AddCert(certFile, keyFile)
+}
+
+// CertReloader serves certificates loaded from files and reloads
+// them when the files change. Certificates are selected by SNI server
+// name matching their DNS names (wildcards included) or common name,
+// the certificate of the first pair is used if no name matches
+type CertReloader struct {
+	pairs   []*certPair
+	names   map[string]*tls.Certificate // by lower case host name
+	onError func(error)
+	mu      *sync.RWMutex
+	stop    chan struct{}
+}
+
+type certPair struct {
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+	certMod  time.Time
+	keyMod   time.Time
+}
+
+// AddCert adds a certFile, keyFile pair, for example: a certificate
+// of another host served by SNI
+func (r *CertReloader) AddCert(certFile string, keyFile string) *CertReloader {
+	r.mu.Lock()
+	r.pairs = append(r.pairs, &certPair{certFile: certFile, keyFile: keyFile})
+	r.mu.Unlock()
+	return r
+}
+
+// OnError sets a hook receiving reload errors of Watch,
+// previously loaded certificates are served after errors
+func (r *CertReloader) OnError(fn func(error)) *CertReloader {
+	r.mu.Lock()
+	r.onError = fn
+	r.mu.Unlock()
+	return r
+}
+
+// Reload loads certificates of all pairs, it returns the first error,
+// certificates of pairs failing to load are not replaced
+func (r *CertReloader) Reload() error {
+	return r.reload(true)
+}
+
+// Watch reloads certificates every interval if their files changed,
+// DefaultCertReloadInterval is used if interval is not positive.
+// It returns the error of the initial load
+func (r *CertReloader) Watch(interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultCertReloadInterval
+	}
+	if err := r.Reload(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	if r.stop != nil {
+		close(r.stop)
+	}
+	stop := make(chan struct{})
+	r.stop = stop
+	r.mu.Unlock()
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+				r.reload(false)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops watching certificate files
+func (r *CertReloader) Stop() {
+	r.mu.Lock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+	r.mu.Unlock()
+}
+
+// GetCertificate returns the certificate of hello server name,
+// it is used as tls.Config.GetCertificate
+func (r *CertReloader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if c, ok := r.names[name]; ok {
+		return c, nil
+	}
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		if c, ok := r.names["*"+name[i:]]; ok {
+			return c, nil
+		}
+	}
+	for _, p := range r.pairs {
+		if p.cert != nil {
+			return p.cert, nil
+		}
+	}
+	return nil, ErrNoCertificates
+}
+
+// reload loads pairs, only pairs with changed files unless all is set
+func (r *CertReloader) reload(all bool) error {
+	r.mu.RLock()
+	pairs := append([]*certPair{}, r.pairs...)
+	onError := r.onError
+	r.mu.RUnlock()
+	var first error
+	changed := false
+	for _, p := range pairs {
+		ok, err := r.load(p, all)
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			if !all {
+				onError(err)
+			}
+		}
+		changed = changed || ok
+	}
+	if changed {
+		r.index()
+	}
+	return first
+}
+
+// load loads p if all is set or its files changed
+func (r *CertReloader) load(p *certPair, all bool) (bool, error) {
+	ci, err := os.Stat(p.certFile)
+	if err != nil {
+		return false, err
+	}
+	ki, err := os.Stat(p.keyFile)
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	unchanged := ci.ModTime().Equal(p.certMod) && ki.ModTime().Equal(p.keyMod)
+	r.mu.RUnlock()
+	if !all && unchanged {
+		return false, nil
+	}
+	cert, err := tls.LoadX509KeyPair(p.certFile, p.keyFile)
+	if err != nil {
+		// files are retried on the next check
+		return false, err
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	p.cert = &cert
+	p.certMod, p.keyMod = ci.ModTime(), ki.ModTime()
+	r.mu.Unlock()
+	return true, nil
+}
+
+// index indexes certificates by host name, earlier pairs win
+func (r *CertReloader) index() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := map[string]*tls.Certificate{}
+	for i := len(r.pairs) - 1; i >= 0; i-- {
+		c := r.pairs[i].cert
+		if c == nil {
+			continue
+		}
+		hosts := c.Leaf.DNSNames
+		if len(hosts) == 0 && c.Leaf.Subject.CommonName != "" {
+			hosts = []string{c.Leaf.Subject.CommonName}
+		}
+		for _, h := range hosts {
+			names[strings.ToLower(h)] = c
+		}
+	}
+	r.names = names
+}
+
+// SetGetCertificate sets the callback selecting certificates of TLS
+// connections served by ListenTLSConfig, for example: the GetCertificate
+// method of CertReloader or an ACME client
+func (s *Server) SetGetCertificate(fn func(*tls.ClientHelloInfo) (*tls.Certificate, error)) *Server {
+	if s.tlsConfig == nil {
+		s.tlsConfig = &tls.Config{}
+	}
+	s.tlsConfig.GetCertificate = fn
+	return s
+}
+
+// ListenTLSReload starts http server and listens on defined addr with
+// TLS reloading the certificate of certFile and keyFile when the files
+// change, reload errors are passed to onError if it is not nil
+func (s *Server) ListenTLSReload(certFile string, keyFile string, onError func(error)) error {
+	r := NewCertReloader(certFile, keyFile)
+	if onError != nil {
+		r.OnError(onError)
+	}
+	if err := r.Watch(DefaultCertReloadInterval); err != nil {
+		return err
+	}
+	defer r.Stop()
+	return s.SetGetCertificate(r.GetCertificate).ListenTLSConfig()
+}
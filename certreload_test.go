@@ -0,0 +1,145 @@
+package jsonapi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestCertReloader(t *testing.T) {
+	suite.Run(t, new(CertReloaderTestSuite))
+}
+
+type CertReloaderTestSuite struct {
+	suite.Suite
+	ca  *testCA
+	dir string
+}
+
+func (t *CertReloaderTestSuite) SetupTest() {
+	var err error
+	t.ca, err = newTestCA()
+	t.NoError(err)
+	t.dir, err = ioutil.TempDir("", "certs")
+	t.NoError(err)
+}
+
+func (t *CertReloaderTestSuite) TearDownTest() {
+	os.RemoveAll(t.dir)
+}
+
+// write issues a certificate of hosts and writes it as name.pem
+// and name.key, mod is the modification time of the files
+func (t *CertReloaderTestSuite) write(name string, serial int64, mod time.Time, hosts ...string) (string, string) {
+	c, err := t.ca.issue(&x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: hosts[0]},
+		DNSNames:     hosts,
+	})
+	t.NoError(err)
+	certPEM, keyPEM, err := encodeKeyPair(c)
+	t.NoError(err)
+	certFile, keyFile := filepath.Join(t.dir, name+".pem"), filepath.Join(t.dir, name+".key")
+	t.NoError(ioutil.WriteFile(certFile, certPEM, 0600))
+	t.NoError(ioutil.WriteFile(keyFile, keyPEM, 0600))
+	t.NoError(os.Chtimes(certFile, mod, mod))
+	t.NoError(os.Chtimes(keyFile, mod, mod))
+	return certFile, keyFile
+}
+
+func (t *CertReloaderTestSuite) serial(r *CertReloader, name string) int64 {
+	c, err := r.GetCertificate(&tls.ClientHelloInfo{ServerName: name})
+	t.NoError(err)
+	return c.Leaf.SerialNumber.Int64()
+}
+
+func (t *CertReloaderTestSuite) TestSNI() {
+	now := time.Now()
+	certA, keyA := t.write("a", 1, now, "a.test")
+	certB, keyB := t.write("b", 2, now, "*.b.test", "b.test")
+	r := NewCertReloader(certA, keyA).AddCert(certB, keyB)
+	_, err := r.GetCertificate(&tls.ClientHelloInfo{})
+	t.Equal(ErrNoCertificates, err)
+	t.NoError(r.Reload())
+
+	t.Equal(int64(1), t.serial(r, "a.test"))
+	t.Equal(int64(2), t.serial(r, "B.test."))
+	t.Equal(int64(2), t.serial(r, "api.b.test"))
+	t.Equal(int64(1), t.serial(r, "other.test"))
+	t.Equal(int64(1), t.serial(r, ""))
+}
+
+func (t *CertReloaderTestSuite) TestReload() {
+	now := time.Now()
+	certFile, keyFile := t.write("a", 1, now, "a.test")
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	r := NewCertReloader(certFile, keyFile).OnError(func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	})
+	t.NoError(r.Watch(10 * time.Millisecond))
+	defer r.Stop()
+	t.Equal(int64(1), t.serial(r, "a.test"))
+
+	t.write("a", 2, now.Add(time.Second), "a.test")
+	t.Eventually(func() bool { return t.serial(r, "a.test") == 2 }, time.Second, 10*time.Millisecond)
+
+	// broken files keep the loaded certificate
+	t.NoError(ioutil.WriteFile(keyFile, []byte("broken"), 0600))
+	t.NoError(os.Chtimes(keyFile, now.Add(2*time.Second), now.Add(2*time.Second)))
+	t.Eventually(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(errs) > 0
+	}, time.Second, 10*time.Millisecond)
+	t.Equal(int64(2), t.serial(r, "a.test"))
+
+	t.write("a", 3, now.Add(3*time.Second), "a.test")
+	t.Eventually(func() bool { return t.serial(r, "a.test") == 3 }, time.Second, 10*time.Millisecond)
+
+	t.Error(NewCertReloader(filepath.Join(t.dir, "missing.pem"), keyFile).Watch(time.Second))
+
+	// intervals that are not positive use the default
+	r = NewCertReloader(certFile, keyFile)
+	t.NoError(r.Watch(0))
+	r.Stop()
+}
+
+func (t *CertReloaderTestSuite) TestListenTLSReload() {
+	now := time.Now()
+	certFile, keyFile := t.write("server", 1, now, "localhost")
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	t.NoError(err)
+	defer ln.Close()
+	s := NewServer().SetListener(ln).Get("/ping", func(ctx *Ctx) { ctx.OK("pong") })
+	go s.ListenTLSReload(certFile, keyFile, nil)
+
+	var serial int64
+	cl := NewClient(ln.Addr().String()).SetTLSConfig(&tls.Config{
+		RootCAs:    t.ca.pool,
+		ServerName: "localhost",
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			serial = cs.PeerCertificates[0].SerialNumber.Int64()
+			return nil
+		},
+	})
+	t.Eventually(func() bool {
+		rs, err := cl.Get("/ping")
+		return err == nil && rs.StatusCode() == StatusOK
+	}, time.Second, 10*time.Millisecond)
+	t.Equal(int64(1), serial)
+}
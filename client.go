@@ -1,7 +1,12 @@
 package jsonapi
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/tls"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/valyala/fasthttp"
 )
@@ -12,42 +17,112 @@ func NewClient(addr string) *Client {
 		addr:     addr,
 		authFunc: func(*Request) {},
 		useSSL:   false,
+		hcMu:     new(sync.Mutex),
 	}
 	return c
 }
 
+var defaultHTTPClient = &fasthttp.Client{}
+
 // Client describes jsonapi client
 type Client struct {
+	rpcID    uint64 // last json-rpc request id, first for atomic alignment
 	addr     string
 	authFunc ClientAuthFunc
+	signFunc ClientSignFunc
+	digest   *digestClient
 	useSSL   bool
+	dial     DialFunc
+	rpcPath  string
+	ctx      context.Context
+	metrics  *Metrics
+	tracer   *Tracer
+
+	tlsConfig *tls.Config
+	hc        *fasthttp.Client // shared client of custom dial or tls config
+	hcMu      *sync.Mutex
 }
 
+// DialFunc is used to establish connections to addr
+type DialFunc func(addr string) (net.Conn, error)
+
 // SetAuthFunc sets authentication modifier function
 func (c *Client) SetAuthFunc(authFunc ClientAuthFunc) *Client {
 	c.authFunc = authFunc
 	return c
 }
 
+// SetSignFunc sets a func signing requests right before
+// they are sent, when the method, uri, body and headers are final
+func (c *Client) SetSignFunc(signFunc ClientSignFunc) *Client {
+	c.signFunc = signFunc
+	return c
+}
+
 // UseSSL forces the client to use https protocol
 func (c *Client) UseSSL() *Client {
 	c.useSSL = true
 	return c
 }
 
+// SetDialFunc sets a func used to establish connections
+// (for example: to connect to an in-memory listener in tests)
+func (c *Client) SetDialFunc(dial DialFunc) *Client {
+	c.dial = dial
+	c.resetHTTPClient()
+	return c
+}
+
 // Request returns a new *Request object
 func (c *Client) Request() *Request {
 	r := new(Request)
+	r.dial = c.dial
+	r.tls = c.tlsConfig
+	r.hc = c.httpClient()
+	r.metrics = c.metrics
+	r.tracer = c.tracer
+	r.sign = c.signFunc
+	r.digest = c.digest
+	r.ctx = c.ctx
+	r.host = c.addr
 	if c.useSSL {
 		r.addr = "https://" + c.addr
 	} else {
 		r.addr = "http://" + c.addr
 	}
+	if c.ctx != nil {
+		// forward request id
+		if v, ok := c.ctx.Value(requestIDContextKey{}).(requestIDValue); ok {
+			r.SetHeader(v.header, v.id)
+		}
+	}
 	// call auth func
 	c.authFunc(r)
 	return r
 }
 
+// httpClient returns the fasthttp client shared by requests of c
+func (c *Client) httpClient() *fasthttp.Client {
+	if c.dial == nil && c.tlsConfig == nil {
+		return defaultHTTPClient
+	}
+	c.hcMu.Lock()
+	defer c.hcMu.Unlock()
+	if c.hc == nil {
+		c.hc = &fasthttp.Client{
+			Dial:      fasthttp.DialFunc(c.dial),
+			TLSConfig: c.tlsConfig,
+		}
+	}
+	return c.hc
+}
+
+func (c *Client) resetHTTPClient() {
+	c.hcMu.Lock()
+	c.hc = nil
+	c.hcMu.Unlock()
+}
+
 // Get is making a http GET request
 func (c *Client) Get(uri string) (*Response, error) {
 	return c.Request().
@@ -57,8 +132,8 @@ func (c *Client) Get(uri string) (*Response, error) {
 }
 
 // Post is making http POST request
-func (c *Client) Post(uri string, body json.Marshaler) (*Response, error) {
-	b, err := body.MarshalJSON()
+func (c *Client) Post(uri string, body interface{}) (*Response, error) {
+	b, err := Marshal(body)
 	if err != nil {
 		return nil, err
 	}
@@ -70,8 +145,8 @@ func (c *Client) Post(uri string, body json.Marshaler) (*Response, error) {
 }
 
 // Put is making http PUT request
-func (c *Client) Put(uri string, body json.Marshaler) (*Response, error) {
-	b, err := body.MarshalJSON()
+func (c *Client) Put(uri string, body interface{}) (*Response, error) {
+	b, err := Marshal(body)
 	if err != nil {
 		return nil, err
 	}
@@ -97,6 +172,15 @@ type Request struct {
 	uri     string            // request uri or path
 	body    []byte            // request body
 	headers map[string]string // request headers
+	dial    DialFunc          // custom dial func
+	metrics *Metrics          // client metrics collector
+	host    string            // target host for metrics
+	tracer  *Tracer           // client tracer
+	ctx     context.Context   // client context
+	sign    ClientSignFunc    // request signer
+	digest  *digestClient     // digest credentials
+	tls     *tls.Config       // client tls config
+	hc      *fasthttp.Client  // shared fasthttp client
 }
 
 // SetMethod is setting request method
@@ -132,6 +216,34 @@ func (r *Request) SetHeader(k string, v string) *Request {
 	return r
 }
 
+// Method returns the request method
+func (r *Request) Method() string {
+	return r.method
+}
+
+// URI returns the request uri
+func (r *Request) URI() string {
+	return r.uri
+}
+
+// Body returns the request body
+func (r *Request) Body() []byte {
+	return r.body
+}
+
+// Header returns the request header k, the name is case insensitive
+func (r *Request) Header(k string) string {
+	if v, ok := r.headers[k]; ok {
+		return v
+	}
+	for name, v := range r.headers {
+		if strings.EqualFold(name, k) {
+			return v
+		}
+	}
+	return ""
+}
+
 func (r *Request) makeURL() string {
 	return r.addr + r.uri
 }
@@ -139,17 +251,71 @@ func (r *Request) makeURL() string {
 // Do executes the http request and returns *Response
 // or error if the request failed
 func (r *Request) Do() (*Response, error) {
+	return r.do(r.digest != nil)
+}
+
+// do executes the request, a Digest challenge
+// of the response is answered once if retry is TRUE
+func (r *Request) do(retry bool) (*Response, error) {
+	span := r.startSpan()
 	req := fasthttp.AcquireRequest()
+	r.prepare(req)
+	res := fasthttp.AcquireResponse()
+	var done func(status string, size int)
+	if r.metrics != nil {
+		done = r.metrics.track(r.metrics.client, r.method, "host", r.host)
+	}
+	if err := r.httpClient(false).Do(req, res); err != nil {
+		if done != nil {
+			done("error", 0)
+		}
+		if span != nil {
+			r.tracer.end(span, 0, err)
+		}
+		return nil, err
+	}
+	if done != nil {
+		done(strconv.Itoa(res.StatusCode()), len(res.Body()))
+	}
+	if span != nil {
+		r.tracer.end(span, res.StatusCode(), nil)
+	}
+	if retry && res.StatusCode() == StatusUnauthorized &&
+		r.digest.setChallenge(string(res.Header.Peek("WWW-Authenticate"))) {
+		fasthttp.ReleaseResponse(res)
+		return r.do(false)
+	}
+	return &Response{res}, nil
+}
+
+// httpClient returns a fasthttp client for the request
+func (r *Request) httpClient(stream bool) *fasthttp.Client {
+	if !stream && r.hc != nil {
+		return r.hc
+	}
+	return &fasthttp.Client{
+		Dial:               fasthttp.DialFunc(r.dial),
+		TLSConfig:          r.tls,
+		StreamResponseBody: stream,
+	}
+}
+
+// prepare copies the request into req
+func (r *Request) prepare(req *fasthttp.Request) {
+	if r.digest != nil {
+		r.digest.authorize(r)
+	}
+	if r.sign != nil {
+		r.sign(r)
+	}
 	req.Header.SetMethod(r.method)
 	req.SetRequestURI(r.makeURL())
+	for k, v := range r.headers {
+		req.Header.Set(k, v)
+	}
 	if r.body != nil {
 		req.SetBody(r.body)
 	}
-	res := fasthttp.AcquireResponse()
-	if err := fasthttp.Do(req, res); err != nil {
-		return nil, err
-	}
-	return &Response{res}, nil
 }
 
 // Response is a wrapper for *fasthttp.Response
@@ -159,6 +325,19 @@ type Response struct {
 }
 
 // ReadJSON reads json into v from request body
-func (r *Response) ReadJSON(v json.Unmarshaler) error {
-	return json.Unmarshal(r.Body(), v)
+func (r *Response) ReadJSON(v interface{}) error {
+	return Unmarshal(r.Body(), v)
+}
+
+// Error returns the error sent by the server as *Error.
+// If the body is not a json error, the http status message is used
+func (r *Response) Error() error {
+	e := new(Error)
+	if err := r.ReadJSON(e); err != nil || e.Err == "" {
+		e = NewErrorString(fasthttp.StatusMessage(r.StatusCode()), r.StatusCode())
+	}
+	if e.Code == 0 {
+		e.Code = r.StatusCode()
+	}
+	return e
 }
@@ -64,6 +64,20 @@ func (t *ClientTestSuite) TestPost() {
 	t.Equal([]byte(`{"some":"data"}`), r.Body())
 }
 
+func (t *ClientTestSuite) TestPostPlain() {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		defer r.Body.Close()
+		t.Equal([]byte(`{"id":1,"name":"Peter"}`), b)
+		w.Write([]byte(`{"id":2,"name":"Tom"}`))
+	}))
+	r, err := NewClient(s.URL[7:]).Post("", plainStruct{1, "Peter"})
+	t.NoError(err)
+	p := new(plainStruct)
+	t.NoError(r.ReadJSON(p))
+	t.Equal(&plainStruct{2, "Tom"}, p)
+}
+
 func (t *ClientTestSuite) TestPut() {
 	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		t.Equal(r.Method, MethodPut)
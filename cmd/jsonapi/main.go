@@ -0,0 +1,48 @@
+// Command jsonapi is a helper tool for jsonapi servers.
+//
+// Usage:
+//
+//	jsonapi routes <addr> [path]
+//
+// routes fetches routes registered by Server.ServeRoutes
+// (default path "/debug/routes") and prints them as a table
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/skamenetskiy/jsonapi"
+)
+
+const defaultRoutesPath = "/debug/routes"
+
+func main() {
+	if len(os.Args) < 3 || os.Args[1] != "routes" {
+		fmt.Fprintln(os.Stderr, "usage: jsonapi routes <addr> [path]")
+		os.Exit(2)
+	}
+	p := defaultRoutesPath
+	if len(os.Args) > 3 {
+		p = os.Args[3]
+	}
+	if err := routes(os.Args[2], p); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func routes(addr string, p string) error {
+	rs, err := jsonapi.NewClient(addr).Get(p)
+	if err != nil {
+		return err
+	}
+	if rs.StatusCode() != jsonapi.StatusOK {
+		return rs.Error()
+	}
+	var res []jsonapi.RouteInfo
+	if err := rs.ReadJSON(&res); err != nil {
+		return err
+	}
+	return jsonapi.PrintRoutes(os.Stdout, res)
+}
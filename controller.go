@@ -24,7 +24,7 @@ type BaseController struct {
 }
 
 // OK returns 200 OK response
-func (c *BaseController) OK(v json.Marshaler) *Result {
+func (c *BaseController) OK(v interface{}) *Result {
 	return &Result{Data: v}
 }
 
@@ -46,16 +46,34 @@ func (c *BaseController) OKList(v []json.Marshaler) *Result {
 	return &Result{Data: ListResult(v)}
 }
 
+// OKStream is a method for returning elements produced by src
+// as a json array streamed to the client
+func (c *BaseController) OKStream(src StreamSource) *Result {
+	return &Result{Data: NewStreamResult(src)}
+}
+
+// OKNDJSON is a method for returning elements produced by src
+// as newline delimited json streamed to the client
+func (c *BaseController) OKNDJSON(src StreamSource) *Result {
+	return &Result{Data: NewNDJSONResult(src)}
+}
+
 // Err returns an error response with http code
 func (c *BaseController) Err(err error, code int) *Result {
 	if err == nil {
 		err = errors.New("unknown error")
 	}
 	return &Result{
-		Err: &Error{err.Error(), code},
+		Err: NewError(err, code),
 	}
 }
 
+// Fail returns an error response, err is mapped to
+// an http error by server error mappers
+func (c *BaseController) Fail(err error) *Result {
+	return &Result{Cause: err}
+}
+
 // ErrBadRequest return http error BadRequest
 func (c *BaseController) ErrBadRequest(err error) *Result {
 	return c.Err(err, StatusBadRequest)
@@ -102,16 +120,17 @@ func (c *BaseController) ErrGatewayTimeout(err error) *Result {
 }
 
 // Result is an object returned from controller method
-// If Err is not nil, an error will be returned to
+// If Err or Cause is not nil, an error will be returned to
 // the client
 type Result struct {
-	Data json.Marshaler
-	Err  *Error
+	Data  interface{} // encoded by Marshal
+	Err   *Error
+	Cause error // mapped to Err by server error mappers if Err is nil
 }
 
 // HasError returns TRUE if the result contains an error
 func (r *Result) HasError() bool {
-	return r.Err != nil
+	return r.Err != nil || r.Cause != nil
 }
 
 // Error sets an err to result and return the result
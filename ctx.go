@@ -1,7 +1,8 @@
 package jsonapi
 
 import (
-	"encoding/json"
+	"crypto/tls"
+	"net"
 	"strconv"
 
 	"github.com/valyala/fasthttp"
@@ -13,14 +14,19 @@ type Ctx struct {
 }
 
 // ReadJSON will try to read request body into v
-func (c *Ctx) ReadJSON(v json.Unmarshaler) error {
-	return json.Unmarshal(c.PostBody(), v)
+func (c *Ctx) ReadJSON(v interface{}) error {
+	return Unmarshal(c.PostBody(), v)
 }
 
 // WriteJSON will try to write v to response body
-// or will output default error if marshal fails
-func (c *Ctx) WriteJSON(v json.Marshaler) {
-	b, err := v.MarshalJSON()
+// or will output default error if marshal fails.
+// A *StreamResult is streamed instead of being buffered
+func (c *Ctx) WriteJSON(v interface{}) {
+	if s, ok := v.(*StreamResult); ok {
+		c.WriteStream(s)
+		return
+	}
+	b, err := Marshal(v)
 	if err != nil {
 		// output default marshal error
 		c.SetStatusCode(StatusInternalServerError)
@@ -68,15 +74,20 @@ func (c *Ctx) GetParamFloat64(k string) (float64, error) {
 
 // OK is writing the response to response body with
 // http status 200OK
-func (c *Ctx) OK(v json.Marshaler) {
+func (c *Ctx) OK(v interface{}) {
 	c.SetStatusCode(StatusOK)
 	c.WriteJSON(v)
 }
 
-// Err is writing an error to response body with code
+// Err is writing an error to response body with code,
+// the error includes the request id
 func (c *Ctx) Err(err error, code int) {
 	c.SetStatusCode(code)
-	c.WriteJSON(Error{err.Error(), code})
+	e := NewError(err, code)
+	if e.RequestID == "" {
+		e.RequestID = c.RequestID()
+	}
+	c.WriteJSON(e)
 }
 
 // ErrBadRequest writes http error BadRequest to response body
@@ -123,3 +134,52 @@ func (c *Ctx) ErrServiceUnavailable(err error) {
 func (c *Ctx) ErrGatewayTimeout(err error) {
 	c.Err(err, StatusGatewayTimeout)
 }
+
+// detach returns a copy of the request of c with its user values,
+// addresses and TLS state, it can be used after the handler returns
+func (c *Ctx) detach() *Ctx {
+	d := new(fasthttp.RequestCtx)
+	d.Init2(newDetachedConn(c), c.Logger(), false)
+	c.Request.CopyTo(&d.Request)
+	c.VisitUserValues(func(k []byte, v interface{}) {
+		d.SetUserValue(string(k), v)
+	})
+	return &Ctx{d}
+}
+
+// detachedConn is the connection of copied request contexts, it has
+// the addresses and TLS state of the original request connection
+type detachedConn struct {
+	net.Conn
+	local  net.Addr
+	remote net.Addr
+}
+
+func (c *detachedConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *detachedConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+type detachedTLSConn struct {
+	*detachedConn
+	state tls.ConnectionState
+}
+
+func (c *detachedTLSConn) Handshake() error {
+	return nil
+}
+
+func (c *detachedTLSConn) ConnectionState() tls.ConnectionState {
+	return c.state
+}
+
+func newDetachedConn(ctx *Ctx) net.Conn {
+	c := &detachedConn{local: ctx.LocalAddr(), remote: ctx.RemoteAddr()}
+	if state := ctx.TLSConnectionState(); state != nil {
+		return &detachedTLSConn{detachedConn: c, state: *state}
+	}
+	return c
+}
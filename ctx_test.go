@@ -80,3 +80,27 @@ func (t *CtxTestSuite) TestGetParamFloat64() {
 	t.NotNil(err)
 	t.Empty(v2)
 }
+
+func (t *CtxTestSuite) TestReadJSON() {
+	ctx := &Ctx{&fasthttp.RequestCtx{}}
+	ctx.Request.SetBody([]byte(`{"id":1,"name":"Peter"}`))
+	p := new(plainStruct)
+	t.NoError(ctx.ReadJSON(p))
+	t.Equal(&plainStruct{1, "Peter"}, p)
+	e := new(Error)
+	ctx.Request.SetBody([]byte(`{"error":"some error"}`))
+	t.NoError(ctx.ReadJSON(e))
+	t.Equal("some error", e.Err)
+}
+
+func (t *CtxTestSuite) TestWriteJSON() {
+	ctx := &Ctx{&fasthttp.RequestCtx{}}
+	ctx.WriteJSON(&Error{Err: "some error"})
+	t.Equal([]byte(`{"error":"some error"}`), ctx.Response.Body())
+	ctx.OK(plainStruct{1, "Peter"})
+	t.Equal(StatusOK, ctx.Response.StatusCode())
+	t.Equal([]byte(`{"id":1,"name":"Peter"}`), ctx.Response.Body())
+	ctx.WriteJSON(make(chan int))
+	t.Equal(StatusInternalServerError, ctx.Response.StatusCode())
+	t.Equal([]byte(`{"error":"failed to marshal json"}`), ctx.Response.Body())
+}
@@ -0,0 +1,322 @@
+package jsonapi
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	DigestMD5    = "MD5"
+	DigestSHA256 = "SHA-256"
+
+	// DefaultDigestNonceTTL is the default lifetime of Digest nonces
+	DefaultDigestNonceTTL = 5 * time.Minute
+)
+
+// DigestHA1 returns the hex HA1 hash of Digest authentication,
+// H(name:realm:password) of algorithm DigestMD5 or DigestSHA256.
+// It is stored as User.Hash in user stores of DigestAuth
+func DigestHA1(algorithm string, name string, realm string, password string) string {
+	return digestHash(algorithm, name+":"+realm+":"+password)
+}
+
+func digestHash(algorithm string, s string) string {
+	var h hash.Hash
+	if algorithm == DigestMD5 {
+		h = md5.New()
+	} else {
+		h = sha256.New()
+	}
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DigestOption configures DigestAuth
+type DigestOption func(*DigestAuth)
+
+// DigestAlgorithm sets the hash algorithm, DigestMD5 or DigestSHA256.
+// HA1 hashes of the user store must use the same algorithm
+func DigestAlgorithm(algorithm string) DigestOption {
+	return func(a *DigestAuth) {
+		a.algorithm = algorithm
+	}
+}
+
+// DigestNonceTTL sets the lifetime of nonces, clients
+// of expired nonces are challenged with stale=true
+func DigestNonceTTL(d time.Duration) DigestOption {
+	return func(a *DigestAuth) {
+		a.ttl = d
+	}
+}
+
+// DigestNonces sets the nonce cache used to reject replayed requests
+func DigestNonces(cache NonceCache) DigestOption {
+	return func(a *DigestAuth) {
+		a.nonces = cache
+	}
+}
+
+// NewDigestAuth creates a HTTP Digest authenticator (RFC 7616, qop=auth)
+// of users of store. Digest can't be verified with password hashes,
+// User.Hash of store is the HA1 hash returned by DigestHA1 for realm,
+// so store is kept apart from stores of BasicAuth. DigestSHA256 is used
+// unless DigestAlgorithm is set, nonces are signed by a random key
+// and a MemoryNonceCache is used unless DigestNonces is set
+func NewDigestAuth(realm string, store UserStore, opts ...DigestOption) *DigestAuth {
+	a := &DigestAuth{
+		realm:     realm,
+		store:     store,
+		algorithm: DigestSHA256,
+		ttl:       DefaultDigestNonceTTL,
+		key:       make([]byte, 32),
+		now:       time.Now,
+	}
+	rand.Read(a.key)
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.nonces == nil {
+		a.nonces = NewMemoryNonceCache()
+	}
+	return a
+}
+
+// DigestAuth verifies HTTP Digest credentials
+type DigestAuth struct {
+	realm     string
+	store     UserStore
+	algorithm string
+	ttl       time.Duration
+	key       []byte // nonce signing key
+	nonces    NonceCache
+	now       func() time.Time
+}
+
+// Authenticate implements Authenticator
+func (a *DigestAuth) Authenticate(ctx *Ctx) (*Principal, error) {
+	auth := ctx.GetHeader("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Digest ") {
+		return nil, AuthChallenge(ErrUnauthorized, a.challenge(false))
+	}
+	p := parseDigestParams(auth[7:])
+	algorithm := p["algorithm"]
+	if algorithm == "" {
+		algorithm = DigestMD5
+	}
+	if p["username"] == "" || p["realm"] != a.realm || algorithm != a.algorithm ||
+		p["qop"] != "auth" || len(p["nc"]) != 8 || p["cnonce"] == "" ||
+		p["uri"] != string(ctx.RequestURI()) {
+		return nil, AuthChallenge(ErrInvalidCredentials, a.challenge(false))
+	}
+	issued, ok := a.verifyNonce(p["nonce"])
+	if !ok {
+		return nil, AuthChallenge(ErrInvalidCredentials, a.challenge(false))
+	}
+	exp := issued.Add(a.ttl)
+	if !a.now().Before(exp) {
+		return nil, AuthChallenge(ErrUnauthorized, a.challenge(true))
+	}
+	u, err := a.store.LookupUser(p["username"])
+	if err != nil {
+		return nil, userStoreError(err)
+	}
+	if u == nil {
+		return nil, AuthChallenge(ErrInvalidCredentials, a.challenge(false))
+	}
+	ha2 := digestHash(a.algorithm, string(ctx.Method())+":"+p["uri"])
+	expected := digestHash(a.algorithm, strings.Join([]string{u.Hash, p["nonce"], p["nc"], p["cnonce"], "auth", ha2}, ":"))
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(p["response"])) != 1 {
+		return nil, AuthChallenge(ErrInvalidCredentials, a.challenge(false))
+	}
+	// every nonce count is accepted once
+	if !a.nonces.Add(p["nonce"]+":"+p["nc"], exp) {
+		return nil, AuthChallenge(ErrReplayedRequest, a.challenge(false))
+	}
+	ctx.SetUserValue(basicUserKey, u)
+	return &Principal{Subject: u.Name, Method: "digest", Roles: u.Roles}, nil
+}
+
+// AuthFunc returns a ServerAuthFunc accepting requests with valid
+// credentials, it sets the user name as the request subject and the
+// WWW-Authenticate challenge of rejected requests
+func (a *DigestAuth) AuthFunc() ServerAuthFunc {
+	return func(ctx *Ctx) bool {
+		p, err := a.Authenticate(ctx)
+		if err != nil {
+			var ce *ChallengeError
+			if errors.As(err, &ce) {
+				ctx.SetHeader("WWW-Authenticate", ce.Challenge)
+			}
+			return false
+		}
+		ctx.SetSubject(p.Subject)
+		return true
+	}
+}
+
+// challenge returns a WWW-Authenticate challenge with a new nonce
+func (a *DigestAuth) challenge(stale bool) string {
+	ch := fmt.Sprintf(`Digest realm=%s, qop="auth", algorithm=%s, nonce="%s"`,
+		quoteDigest(a.realm), a.algorithm, a.newNonce())
+	if stale {
+		ch += ", stale=true"
+	}
+	return ch
+}
+
+// newNonce returns a nonce of the issue time signed by a.key
+func (a *DigestAuth) newNonce() string {
+	ts := strconv.FormatInt(a.now().UnixNano(), 16)
+	return ts + "." + base64.RawURLEncoding.EncodeToString(hmacSum(a.key, []byte(ts)))
+}
+
+// verifyNonce returns the issue time of nonce and TRUE if it is signed by a.key
+func (a *DigestAuth) verifyNonce(nonce string) (time.Time, bool) {
+	i := strings.IndexByte(nonce, '.')
+	if i < 0 {
+		return time.Time{}, false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(nonce[i+1:])
+	if err != nil || !hmac.Equal(sig, hmacSum(a.key, []byte(nonce[:i]))) {
+		return time.Time{}, false
+	}
+	ts, err := strconv.ParseInt(nonce[:i], 16, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, ts), true
+}
+
+func quoteDigest(s string) string {
+	return `"` + strings.Replace(strings.Replace(s, `\`, `\\`, -1), `"`, `\"`, -1) + `"`
+}
+
+// parseDigestParams parses comma separated name=value parameters
+// of Digest headers, values may be quoted strings
+func parseDigestParams(s string) map[string]string {
+	p := map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		i := strings.IndexByte(s, '=')
+		if i <= 0 {
+			return p
+		}
+		name := strings.ToLower(strings.TrimSpace(s[:i]))
+		s = strings.TrimLeft(s[i+1:], " \t")
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i = 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			value = b.String()
+			if i < len(s) {
+				i++ // closing quote
+			}
+			s = s[i:]
+		} else {
+			i = strings.IndexByte(s, ',')
+			if i < 0 {
+				i = len(s)
+			}
+			value = strings.TrimSpace(s[:i])
+			s = s[i:]
+		}
+		p[name] = value
+	}
+}
+
+// SetDigestAuth sets Digest credentials of the client. Requests are
+// sent once without credentials, a Digest challenge of the response
+// is answered by retrying the request, later requests reuse the
+// challenge until the server sends a new one
+func (c *Client) SetDigestAuth(name string, password string) *Client {
+	c.digest = &digestClient{name: name, password: password, mu: new(sync.Mutex)}
+	return c
+}
+
+// digestClient keeps the last Digest challenge of a client
+type digestClient struct {
+	name      string
+	password  string
+	mu        *sync.Mutex
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	nc        uint32
+}
+
+// setChallenge keeps the Digest challenge of a 401 response,
+// it returns FALSE if the response has no usable challenge
+func (d *digestClient) setChallenge(challenge string) bool {
+	if len(challenge) < 7 || !strings.EqualFold(challenge[:7], "Digest ") {
+		return false
+	}
+	p := parseDigestParams(challenge[7:])
+	algorithm := p["algorithm"]
+	if algorithm == "" {
+		algorithm = DigestMD5
+	}
+	if p["nonce"] == "" || (algorithm != DigestMD5 && algorithm != DigestSHA256) ||
+		!contains(strings.Split(strings.Replace(p["qop"], " ", "", -1), ","), "auth") {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.realm, d.nonce, d.opaque, d.algorithm, d.nc = p["realm"], p["nonce"], p["opaque"], algorithm, 0
+	return true
+}
+
+// authorize sets the Authorization header of r if a challenge is known
+func (d *digestClient) authorize(r *Request) {
+	d.mu.Lock()
+	if d.nonce == "" {
+		d.mu.Unlock()
+		return
+	}
+	d.nc++
+	realm, nonce, opaque, algorithm := d.realm, d.nonce, d.opaque, d.algorithm
+	nc := fmt.Sprintf("%08x", d.nc)
+	d.mu.Unlock()
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	cnonce := hex.EncodeToString(b)
+	uri := fasthttp.AcquireURI()
+	uri.Update(r.URI())
+	requestURI := string(uri.RequestURI())
+	fasthttp.ReleaseURI(uri)
+	method := r.Method()
+	if method == "" {
+		method = MethodGet
+	}
+	ha1 := DigestHA1(algorithm, d.name, realm, d.password)
+	ha2 := digestHash(algorithm, method+":"+requestURI)
+	response := digestHash(algorithm, strings.Join([]string{ha1, nonce, nc, cnonce, "auth", ha2}, ":"))
+	auth := fmt.Sprintf(`Digest username=%s, realm=%s, nonce=%s, uri=%s, algorithm=%s, qop=auth, nc=%s, cnonce="%s", response="%s"`,
+		quoteDigest(d.name), quoteDigest(realm), quoteDigest(nonce), quoteDigest(requestURI), algorithm, nc, cnonce, response)
+	if opaque != "" {
+		auth += ", opaque=" + quoteDigest(opaque)
+	}
+	r.SetHeader("Authorization", auth)
+}
@@ -0,0 +1,118 @@
+package jsonapi
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestDigestAuth(t *testing.T) {
+	suite.Run(t, new(DigestAuthTestSuite))
+}
+
+type DigestAuthTestSuite struct {
+	suite.Suite
+}
+
+func (t *DigestAuthTestSuite) TestParams() {
+	p := parseDigestParams(` username="a\"b", realm="x, y",nc=00000001 , qop=auth,empty=""`)
+	t.Equal(map[string]string{"username": `a"b`, "realm": "x, y", "nc": "00000001", "qop": "auth", "empty": ""}, p)
+	t.Empty(parseDigestParams("invalid"))
+	t.Equal(map[string]string{"a": "unterminated"}, parseDigestParams(`a="unterminated`))
+}
+
+func (t *DigestAuthTestSuite) TestServerAndClient() {
+	store := NewMemoryUserStore(&User{Name: "alice", Hash: DigestHA1(DigestSHA256, "alice", "internal", "pass"), Roles: []string{"admin"}})
+	a := NewDigestAuth("internal", store)
+	mu := new(sync.Mutex)
+	now := time.Now()
+	a.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).SetAuthenticator(a).
+		Get("/me", func(ctx *Ctx) { ctx.OK(ctx.Principal().Method + ":" + ctx.User().Name) }, RequireRoles("admin"))
+	go s.Listen()
+	defer ln.Close()
+	var auth string
+	client := func(name string, password string) *Client {
+		return NewClient(ln.Addr().String()).
+			SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }).
+			SetDigestAuth(name, password).
+			SetSignFunc(func(r *Request) { auth = r.Header("Authorization") })
+	}
+
+	cl := client("alice", "pass")
+	for _, uri := range []string{"/me", "/me?x=1"} {
+		rs, err := cl.Get(uri)
+		t.NoError(err)
+		t.Equal(StatusOK, rs.StatusCode(), uri)
+		t.Equal(`"digest:alice"`, string(rs.Body()))
+	}
+	// the challenge is reused with the next nonce count
+	t.Contains(auth, "nc=00000002")
+	t.Contains(auth, `uri="/me?x=1"`)
+
+	// replayed and altered requests are rejected
+	raw := NewClient(ln.Addr().String()).SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() })
+	rs, err := raw.Request().SetURI("/me?x=1").SetHeader("Authorization", auth).Do()
+	t.NoError(err)
+	t.Equal(StatusUnauthorized, rs.StatusCode())
+	t.Equal(ErrReplayedRequest.Error(), rs.Error().(*Error).Err)
+	rs, err = raw.Request().SetURI("/me?x=2").SetHeader("Authorization", auth).Do()
+	t.NoError(err)
+	t.Equal(StatusUnauthorized, rs.StatusCode())
+	t.Equal(ErrInvalidCredentials.Error(), rs.Error().(*Error).Err)
+
+	// expired nonces are renewed with a stale challenge
+	mu.Lock()
+	now = now.Add(DefaultDigestNonceTTL)
+	mu.Unlock()
+	rs, err = cl.Get("/me")
+	t.NoError(err)
+	t.Equal(StatusOK, rs.StatusCode())
+	t.Contains(auth, "nc=00000001")
+
+	for _, cl := range []*Client{client("alice", "wrong"), client("bob", "pass"), raw} {
+		rs, err = cl.Get("/me")
+		t.NoError(err)
+		t.Equal(StatusUnauthorized, rs.StatusCode())
+		ch := string(rs.Header.Peek("WWW-Authenticate"))
+		t.True(strings.HasPrefix(ch, `Digest realm="internal", qop="auth", algorithm=SHA-256, nonce="`), ch)
+		t.NotContains(ch, "stale")
+	}
+}
+
+func (t *DigestAuthTestSuite) TestAuthFunc() {
+	store := NewMemoryUserStore(&User{Name: "alice", Hash: DigestHA1(DigestMD5, "alice", "tools", "pass")})
+	a := NewDigestAuth("tools", store, DigestAlgorithm(DigestMD5))
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).
+		Post("/me", func(ctx *Ctx) { ctx.OK(ctx.Subject()) })
+	s.SetAuthFunc(a.AuthFunc())
+	go s.Listen()
+	defer ln.Close()
+	cl := NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }).
+		SetDigestAuth("alice", "pass")
+	rs, err := cl.Post("/me", []byte(`{}`))
+	t.NoError(err)
+	t.Equal(StatusOK, rs.StatusCode())
+	t.Equal(`"alice"`, string(rs.Body()))
+
+	// basic credentials are answered with a digest challenge
+	rs, err = NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }).
+		SetAuthFunc(BasicClientAuth("alice", "pass")).
+		Post("/me", nil)
+	t.NoError(err)
+	t.Equal(StatusUnauthorized, rs.StatusCode())
+	t.Contains(string(rs.Header.Peek("WWW-Authenticate")), "algorithm=MD5")
+}
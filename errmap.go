@@ -0,0 +1,130 @@
+package jsonapi
+
+import (
+	"errors"
+	"reflect"
+)
+
+const (
+	causeKey  = "jsonapi.cause"
+	serverKey = "jsonapi.server"
+)
+
+var (
+	ErrInternal = errors.New("internal server error")
+)
+
+// ErrorMapper maps err to *Error or returns nil if err is not mapped
+type ErrorMapper func(err error) *Error
+
+// MapError maps errors matching target with errors.Is
+// to an *Error with code and the error message
+func (s *Server) MapError(target error, code int) *Server {
+	return s.MapErrorFunc(func(err error) *Error {
+		if errors.Is(err, target) {
+			return NewError(err, code)
+		}
+		return nil
+	})
+}
+
+// MapErrorType maps errors matching the type of target with errors.As
+// to an *Error with code and the error message. target is a value
+// of the error type, for example: (*ValidationError)(nil).
+// A nil target is a registration error returned by Err
+func (s *Server) MapErrorType(target error, code int) *Server {
+	if target == nil {
+		s.addErr(errors.New("error type of MapErrorType is nil"))
+		return s
+	}
+	t := reflect.TypeOf(target)
+	return s.MapErrorFunc(func(err error) *Error {
+		if errors.As(err, reflect.New(t).Interface()) {
+			return NewError(err, code)
+		}
+		return nil
+	})
+}
+
+// MapErrorFunc adds an error mapper. Mappers are applied to errors
+// returned by handlers in Result.Cause in order of addition,
+// the first non nil *Error is returned to the client
+func (s *Server) MapErrorFunc(fn ErrorMapper) *Server {
+	s.mu.Lock()
+	s.errorMappers = append(s.errorMappers, fn)
+	s.mu.Unlock()
+	return s
+}
+
+// mapError converts err into *Error using error mappers, *Error
+// found in err chain is returned as is. Unmapped errors are returned
+// with code and the error message if code is set, otherwise
+// as ErrInternal with StatusInternalServerError and err is kept
+// as the cause so internal details aren't sent to the client.
+// s may be nil for handlers served without a server, errors
+// aren't mapped then
+func (s *Server) mapError(err error, code ...int) *Error {
+	if e := s.findError(err); e != nil {
+		return e
+	}
+	if len(code) > 0 {
+		return NewError(err, code[0])
+	}
+	e := NewError(ErrInternal, StatusInternalServerError)
+	e.cause = err
+	return e
+}
+
+// findError returns *Error found in err chain or returned
+// by error mappers, nil is returned for unmapped errors
+func (s *Server) findError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var ev Error
+	if errors.As(err, &ev) {
+		return &ev
+	}
+	var mappers []ErrorMapper
+	if s != nil {
+		s.mu.Lock()
+		mappers = s.errorMappers
+		s.mu.Unlock()
+	}
+	for _, fn := range mappers {
+		if e := fn(err); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// requestError returns a copy of err mapped by mapError with the request
+// id of c, err is kept as the cause of the failed request
+func (s *Server) requestError(c *Ctx, err error) *Error {
+	c.SetUserValue(causeKey, err)
+	return s.errorWithID(err, c.RequestID())
+}
+
+// errorWithID returns a copy of err mapped by mapError with requestID
+func (s *Server) errorWithID(err error, requestID string) *Error {
+	e := s.mapError(err)
+	e = NewError(e, e.Code)
+	if e.RequestID == "" {
+		e.RequestID = requestID
+	}
+	return e
+}
+
+// server returns the server of the request or nil
+func (c *Ctx) server() *Server {
+	s, _ := c.UserValue(serverKey).(*Server)
+	return s
+}
+
+// errCause returns Result.Cause of a failed request
+func (c *Ctx) errCause() error {
+	err, _ := c.UserValue(causeKey).(error)
+	return err
+}
@@ -0,0 +1,106 @@
+package jsonapi
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestErrorMap(t *testing.T) {
+	suite.Run(t, new(ErrorMapTestSuite))
+}
+
+type ErrorMapTestSuite struct {
+	suite.Suite
+}
+
+var errConflict = errors.New("conflict")
+
+type fieldError struct {
+	Field string
+}
+
+func (e *fieldError) Error() string { return "invalid " + e.Field }
+
+type errController struct {
+	BaseController
+}
+
+func (c *errController) Methods() ControllerMethods {
+	return ControllerMethods{
+		MethodGet: {
+			"/missing":  func(*Ctx) *Result { return c.Fail(fmt.Errorf("user: %w", sql.ErrNoRows)) },
+			"/conflict": func(*Ctx) *Result { return c.Fail(errConflict) },
+			"/field":    func(*Ctx) *Result { return c.Fail(fmt.Errorf("wrap: %w", &fieldError{"name"})) },
+			"/teapot":   func(*Ctx) *Result { return c.Fail(errors.New("teapot")) },
+			"/explicit": func(*Ctx) *Result { return c.ErrForbidden(errConflict) },
+			"/unmapped": func(*Ctx) *Result { return c.Fail(errors.New("boom")) },
+			"/wrapped":  func(*Ctx) *Result { return c.Fail(fmt.Errorf("wrap: %w", NewErrorString("gone", 410))) },
+			"/typed":    Typed(func(*Ctx) (int, error) { return 0, errConflict }),
+		},
+	}
+}
+
+func (t *ErrorMapTestSuite) TestMapError() {
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).
+		MapError(sql.ErrNoRows, StatusNotFound).
+		MapError(errConflict, 409).
+		MapErrorType((*fieldError)(nil), 422).
+		MapErrorFunc(func(err error) *Error {
+			if err.Error() == "teapot" {
+				return NewErrorString("i'm a teapot", 418)
+			}
+			return nil
+		}).
+		Controller("/", new(errController))
+	go s.Listen()
+	defer ln.Close()
+	cl := NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() })
+	tests := map[string]int{
+		"/missing":  StatusNotFound,
+		"/conflict": 409,
+		"/field":    422,
+		"/teapot":   418,
+		"/explicit": StatusForbidden,
+		"/unmapped": StatusInternalServerError,
+		"/typed":    409,
+		"/wrapped":  410,
+	}
+	for uri, code := range tests {
+		rs, err := cl.Get(uri)
+		t.NoError(err)
+		t.Equal(code, rs.StatusCode(), uri)
+	}
+	// unmapped errors are not sent to the client
+	rs, err := cl.Get("/unmapped")
+	t.NoError(err)
+	t.Equal(ErrInternal.Error(), rs.Error().(*Error).Err)
+}
+
+func (t *ErrorMapTestSuite) TestMapErrorDefault() {
+	s := NewServer()
+	e500 := s.mapError(errConflict)
+	t.Equal(StatusInternalServerError, e500.Code)
+	t.Equal("internal server error", e500.Err)
+	t.True(errors.Is(e500, errConflict))
+	e := NewErrorString("bad", StatusBadRequest)
+	t.Equal(e, s.mapError(e))
+	t.Equal(StatusBadRequest, s.mapError(*e).Code)
+	t.True((&Result{Cause: errConflict}).HasError())
+	wrapped := fmt.Errorf("wrap: %w", e)
+	t.Equal(e, s.mapError(wrapped))
+	t.Equal(StatusBadRequest, s.mapError(fmt.Errorf("wrap: %w", *e)).Code)
+}
+
+func (t *ErrorMapTestSuite) TestMapErrorTypeNil() {
+	s := NewServer()
+	t.NotPanics(func() { s.MapErrorType(nil, StatusBadRequest) })
+	t.Error(s.Err())
+}
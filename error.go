@@ -6,15 +6,22 @@ import (
 )
 
 var (
-	ErrUnauthorized = NewErrorString("unauthorized", StatusUnauthorized)
+	ErrUnauthorized = NewErrorString("unauthorized", StatusUnauthorized).WithAppCode("unauthorized")
+	ErrForbidden    = NewErrorString("forbidden", StatusForbidden).WithAppCode("forbidden")
+
+	ErrTooManyRequests = NewErrorString("too many requests", StatusTooManyRequests).WithAppCode("rate_limited")
 )
 
 // Error is a custom error object
 //go:generate easyjson
 //easyjson:json
 type Error struct {
-	Err  string `json:"error"`
-	Code int    `json:"code,omitempty"`
+	Err       string                 `json:"error"`
+	Code      int                    `json:"code,omitempty"`
+	AppCode   string                 `json:"appCode,omitempty"` // application error code, for example: animal_not_found
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"requestId,omitempty"`
+	cause     error
 }
 
 // Error implements error interface
@@ -22,10 +29,64 @@ func (e Error) Error() string {
 	return fmt.Sprintf("%d: %s", e.Code, e.Err)
 }
 
-// NewError returns a new *Error object
+// Unwrap returns the original error, it is not sent to the client
+func (e Error) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether target is an *Error with the same AppCode,
+// or the same Code and message if target has no AppCode.
+// It works with errors decoded by the client
+func (e Error) Is(target error) bool {
+	var t *Error
+	switch v := target.(type) {
+	case *Error:
+		t = v
+	case Error:
+		t = &v
+	default:
+		return false
+	}
+	if t.AppCode != "" {
+		return e.AppCode == t.AppCode
+	}
+	return e.Code == t.Code && e.Err == t.Err
+}
+
+// WithAppCode returns a copy of the error with application error code
+func (e *Error) WithAppCode(appCode string) *Error {
+	c := *e
+	c.AppCode = appCode
+	return &c
+}
+
+// WithDetails returns a copy of the error with details added
+func (e *Error) WithDetails(details map[string]interface{}) *Error {
+	c := *e
+	c.Details = make(map[string]interface{}, len(e.Details)+len(details))
+	for k, v := range e.Details {
+		c.Details[k] = v
+	}
+	for k, v := range details {
+		c.Details[k] = v
+	}
+	return &c
+}
+
+// NewError returns a new *Error object wrapping err.
+// If err is an *Error, its message, app code, details and
+// request id are kept
 func NewError(err error, code ...int) *Error {
 	e := new(Error)
-	e.Err = err.Error()
+	switch v := err.(type) {
+	case *Error:
+		*e = *v
+	case Error:
+		*e = v
+	default:
+		e.Err = err.Error()
+	}
+	e.cause = err
 	if len(code) > 0 {
 		e.Code = code[0]
 	}
@@ -28,7 +28,7 @@ func easyjsonE34310f8DecodeGithubComSkamenetskiyJsonapi(in *jlexer.Lexer, out *E
 	}
 	in.Delim('{')
 	for !in.IsDelim('}') {
-		key := in.UnsafeString()
+		key := in.UnsafeFieldName(false)
 		in.WantColon()
 		if in.IsNull() {
 			in.Skip()
@@ -40,6 +40,36 @@ func easyjsonE34310f8DecodeGithubComSkamenetskiyJsonapi(in *jlexer.Lexer, out *E
 			out.Err = string(in.String())
 		case "code":
 			out.Code = int(in.Int())
+		case "appCode":
+			out.AppCode = string(in.String())
+		case "details":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				if !in.IsDelim('}') {
+					out.Details = make(map[string]interface{})
+				} else {
+					out.Details = nil
+				}
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v1 interface{}
+					if m, ok := v1.(easyjson.Unmarshaler); ok {
+						m.UnmarshalEasyJSON(in)
+					} else if m, ok := v1.(json.Unmarshaler); ok {
+						_ = m.UnmarshalJSON(in.Raw())
+					} else {
+						v1 = in.Interface()
+					}
+					(out.Details)[key] = v1
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		case "requestId":
+			out.RequestID = string(in.String())
 		default:
 			in.SkipRecursive()
 		}
@@ -56,24 +86,49 @@ func easyjsonE34310f8EncodeGithubComSkamenetskiyJsonapi(out *jwriter.Writer, in
 	_ = first
 	{
 		const prefix string = ",\"error\":"
-		if first {
-			first = false
-			out.RawString(prefix[1:])
-		} else {
-			out.RawString(prefix)
-		}
+		out.RawString(prefix[1:])
 		out.String(string(in.Err))
 	}
 	if in.Code != 0 {
 		const prefix string = ",\"code\":"
-		if first {
-			first = false
-			out.RawString(prefix[1:])
-		} else {
-			out.RawString(prefix)
-		}
+		out.RawString(prefix)
 		out.Int(int(in.Code))
 	}
+	if in.AppCode != "" {
+		const prefix string = ",\"appCode\":"
+		out.RawString(prefix)
+		out.String(string(in.AppCode))
+	}
+	if len(in.Details) != 0 {
+		const prefix string = ",\"details\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('{')
+			v2First := true
+			for v2Name, v2Value := range in.Details {
+				if v2First {
+					v2First = false
+				} else {
+					out.RawByte(',')
+				}
+				out.String(string(v2Name))
+				out.RawByte(':')
+				if m, ok := v2Value.(easyjson.Marshaler); ok {
+					m.MarshalEasyJSON(out)
+				} else if m, ok := v2Value.(json.Marshaler); ok {
+					out.Raw(m.MarshalJSON())
+				} else {
+					out.Raw(json.Marshal(v2Value))
+				}
+			}
+			out.RawByte('}')
+		}
+	}
+	if in.RequestID != "" {
+		const prefix string = ",\"requestId\":"
+		out.RawString(prefix)
+		out.String(string(in.RequestID))
+	}
 	out.RawByte('}')
 }
 
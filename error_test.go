@@ -2,9 +2,12 @@ package jsonapi
 
 import (
 	"errors"
+	"fmt"
+	"net"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
 )
 
 func TestError(t *testing.T) {
@@ -51,3 +54,58 @@ func (t *ErrorTestSuite) TestUnmarshalJSON() {
 	t.Equal(e.Err, "big error")
 	t.Equal(e.Code, 678)
 }
+
+func (t *ErrorTestSuite) TestUnwrap() {
+	err := errors.New("some error")
+	e := NewError(fmt.Errorf("wrapped: %w", err), StatusNotFound)
+	t.True(errors.Is(e, err))
+	t.Equal("wrapped: some error", e.Err)
+	t.Nil(NewErrorString("some error").Unwrap())
+}
+
+func (t *ErrorTestSuite) TestIs() {
+	e := NewErrorString("animal 1 not found", StatusNotFound).WithAppCode("animal_not_found")
+	target := NewErrorString("animal not found", StatusNotFound).WithAppCode("animal_not_found")
+	t.True(errors.Is(e, target))
+	t.False(errors.Is(e, ErrUnauthorized))
+	t.True(errors.Is(NewError(e, StatusBadRequest), target))
+	t.True(errors.Is(NewErrorString("x", 1), NewErrorString("x", 1)))
+	t.False(errors.Is(NewErrorString("x", 1), NewErrorString("x", 2)))
+	// decoded errors keep app codes
+	b, err := NewError(ErrUnauthorized).MarshalJSON()
+	t.NoError(err)
+	decoded := new(Error)
+	t.NoError(decoded.UnmarshalJSON(b))
+	t.True(errors.Is(decoded, ErrUnauthorized))
+	t.Equal("unauthorized", decoded.Err)
+}
+
+func (t *ErrorTestSuite) TestDetails() {
+	e := NewErrorString("invalid animal", StatusBadRequest).
+		WithAppCode("invalid_animal").
+		WithDetails(map[string]interface{}{"field": "name"})
+	e.RequestID = "req-1"
+	b, err := e.MarshalJSON()
+	t.NoError(err)
+	t.Equal(`{"error":"invalid animal","code":400,"appCode":"invalid_animal","details":{"field":"name"},"requestId":"req-1"}`, string(b))
+	e2 := e.WithDetails(map[string]interface{}{"min": 1})
+	t.Len(e.Details, 1)
+	t.Len(e2.Details, 2)
+	e3 := NewError(e, StatusForbidden)
+	t.Equal("invalid_animal", e3.AppCode)
+	t.Equal("req-1", e3.RequestID)
+	t.Equal(StatusForbidden, e3.Code)
+}
+
+func (t *ErrorTestSuite) TestIsClient() {
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).Get("/a1", func(ctx *Ctx) {})
+	s.SetAuthFunc(func(*Ctx) bool { return false })
+	go s.Listen()
+	defer ln.Close()
+	rs, err := NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }).
+		Get("/a1")
+	t.NoError(err)
+	t.True(errors.Is(rs.Error(), ErrUnauthorized))
+}
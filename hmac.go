@@ -0,0 +1,287 @@
+package jsonapi
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// HMACScheme is the Authorization scheme of signed requests
+	HMACScheme = "HMAC-SHA256"
+
+	// DefaultHMACSkew is the default allowed clock skew of signed requests
+	DefaultHMACSkew = 5 * time.Minute
+
+	hmacKeyIDKey = "jsonapi.hmackey"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid request signature")
+	ErrSignatureExpired = errors.New("request signature is expired")
+	ErrReplayedRequest  = errors.New("replayed request")
+)
+
+// HMACSigner returns a ClientSignFunc signing requests with secret.
+// The signature covers the method, request uri, timestamp, a random
+// nonce, values of headers and the SHA-256 hash of the body, it is
+// sent in the Authorization header:
+//
+//	HMAC-SHA256 key=id,ts=unix,nonce=hex,headers=a;b,signature=base64
+func HMACSigner(keyID string, secret []byte, headers ...string) ClientSignFunc {
+	names := make([]string, len(headers))
+	for i, h := range headers {
+		names[i] = strings.ToLower(h)
+	}
+	return func(r *Request) {
+		nonce := make([]byte, 16)
+		rand.Read(nonce)
+		p := hmacParams{
+			key:     keyID,
+			ts:      strconv.FormatInt(time.Now().Unix(), 10),
+			nonce:   hex.EncodeToString(nonce),
+			headers: names,
+		}
+		values := make([]string, len(names))
+		for i, h := range names {
+			values[i] = r.Header(h)
+		}
+		uri := fasthttp.AcquireURI()
+		uri.Update(r.URI())
+		method := r.Method()
+		if method == "" {
+			method = MethodGet
+		}
+		msg := hmacMessage(method, uri.RequestURI(), p, values, r.Body())
+		fasthttp.ReleaseURI(uri)
+		p.signature = base64.RawURLEncoding.EncodeToString(hmacSum(secret, msg))
+		r.SetHeader("Authorization", p.String())
+	}
+}
+
+// NonceCache remembers nonces of signed requests
+type NonceCache interface {
+	// Add adds nonce expiring at exp, it returns FALSE
+	// if the nonce is already known and not expired
+	Add(nonce string, exp time.Time) bool
+}
+
+// NewMemoryNonceCache creates an in-memory NonceCache
+func NewMemoryNonceCache() *MemoryNonceCache {
+	return &MemoryNonceCache{nonces: map[string]time.Time{}, mu: new(sync.Mutex)}
+}
+
+// MemoryNonceCache is an in-memory NonceCache,
+// expired nonces are removed while adding new ones
+type MemoryNonceCache struct {
+	nonces map[string]time.Time
+	mu     *sync.Mutex
+	adds   int
+}
+
+// Add implements NonceCache
+func (c *MemoryNonceCache) Add(nonce string, exp time.Time) bool {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.nonces[nonce]; ok && now.Before(e) {
+		return false
+	}
+	c.nonces[nonce] = exp
+	c.adds++
+	if c.adds >= 1000 {
+		c.adds = 0
+		for n, e := range c.nonces {
+			if !now.Before(e) {
+				delete(c.nonces, n)
+			}
+		}
+	}
+	return true
+}
+
+// HMACOption configures HMACAuth
+type HMACOption func(*HMACAuth)
+
+// HMACSkew sets the allowed clock skew of request timestamps
+func HMACSkew(d time.Duration) HMACOption {
+	return func(a *HMACAuth) {
+		a.skew = d
+	}
+}
+
+// HMACNonces sets the nonce cache used to reject replayed requests
+func HMACNonces(cache NonceCache) HMACOption {
+	return func(a *HMACAuth) {
+		a.nonces = cache
+	}
+}
+
+// HMACRequireHeaders rejects requests not signing headers
+func HMACRequireHeaders(headers ...string) HMACOption {
+	return func(a *HMACAuth) {
+		for _, h := range headers {
+			a.required = append(a.required, strings.ToLower(h))
+		}
+	}
+}
+
+// NewHMACAuth creates an authenticator of requests signed by
+// HMACSigner with secrets by key id. A MemoryNonceCache is used
+// unless HMACNonces is set
+func NewHMACAuth(keys map[string][]byte, opts ...HMACOption) *HMACAuth {
+	a := &HMACAuth{keys: keys, skew: DefaultHMACSkew, now: time.Now}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.nonces == nil {
+		a.nonces = NewMemoryNonceCache()
+	}
+	return a
+}
+
+// HMACAuth verifies signed requests
+type HMACAuth struct {
+	keys     map[string][]byte // by key id
+	skew     time.Duration
+	nonces   NonceCache
+	required []string
+	now      func() time.Time
+}
+
+// Authenticate implements Authenticator, the principal
+// subject is the key id of the request
+func (a *HMACAuth) Authenticate(ctx *Ctx) (*Principal, error) {
+	auth := ctx.GetHeader("Authorization")
+	if !strings.HasPrefix(auth, HMACScheme+" ") {
+		return nil, AuthChallenge(ErrUnauthorized, HMACScheme)
+	}
+	p, ok := parseHMACParams(auth[len(HMACScheme)+1:])
+	if !ok {
+		return nil, AuthChallenge(ErrInvalidSignature, HMACScheme)
+	}
+	secret, ok := a.keys[p.key]
+	if !ok {
+		return nil, AuthChallenge(ErrInvalidSignature, HMACScheme)
+	}
+	for _, h := range a.required {
+		if !contains(p.headers, h) {
+			return nil, AuthChallenge(ErrInvalidSignature, HMACScheme)
+		}
+	}
+	values := make([]string, len(p.headers))
+	for i, h := range p.headers {
+		values[i] = ctx.GetHeader(h)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(p.signature)
+	msg := hmacMessage(string(ctx.Method()), ctx.URI().RequestURI(), p, values, ctx.PostBody())
+	if err != nil || !hmac.Equal(sig, hmacSum(secret, msg)) {
+		return nil, AuthChallenge(ErrInvalidSignature, HMACScheme)
+	}
+	ts, _ := strconv.ParseInt(p.ts, 10, 64)
+	now := a.now()
+	signed := time.Unix(ts, 0)
+	if signed.Before(now.Add(-a.skew)) || signed.After(now.Add(a.skew)) {
+		return nil, AuthChallenge(ErrSignatureExpired, HMACScheme)
+	}
+	// requests older than the skew window are rejected anyway
+	if !a.nonces.Add(p.key+":"+p.nonce, signed.Add(a.skew)) {
+		return nil, AuthChallenge(ErrReplayedRequest, HMACScheme)
+	}
+	ctx.SetUserValue(hmacKeyIDKey, p.key)
+	return &Principal{Subject: p.key, Method: "hmac"}, nil
+}
+
+// AuthFunc returns a ServerAuthFunc accepting signed requests,
+// it sets the key id as the request subject
+func (a *HMACAuth) AuthFunc() ServerAuthFunc {
+	return func(ctx *Ctx) bool {
+		p, err := a.Authenticate(ctx)
+		if err != nil {
+			return false
+		}
+		ctx.SetSubject(p.Subject)
+		return true
+	}
+}
+
+// HMACKeyID returns the key id of a verified signed request
+func (c *Ctx) HMACKeyID() string {
+	id, _ := c.UserValue(hmacKeyIDKey).(string)
+	return id
+}
+
+// hmacParams are parameters of the Authorization header
+type hmacParams struct {
+	key       string
+	ts        string
+	nonce     string
+	headers   []string
+	signature string
+}
+
+func (p hmacParams) String() string {
+	return HMACScheme + " key=" + p.key + ",ts=" + p.ts + ",nonce=" + p.nonce +
+		",headers=" + strings.Join(p.headers, ";") + ",signature=" + p.signature
+}
+
+func parseHMACParams(s string) (hmacParams, bool) {
+	var p hmacParams
+	for _, kv := range strings.Split(s, ",") {
+		i := strings.IndexByte(kv, '=')
+		if i < 0 {
+			return p, false
+		}
+		v := kv[i+1:]
+		switch strings.TrimSpace(kv[:i]) {
+		case "key":
+			p.key = v
+		case "ts":
+			p.ts = v
+		case "nonce":
+			p.nonce = v
+		case "headers":
+			if v != "" {
+				p.headers = strings.Split(v, ";")
+			}
+		case "signature":
+			p.signature = v
+		}
+	}
+	if _, err := strconv.ParseInt(p.ts, 10, 64); err != nil {
+		return p, false
+	}
+	return p, p.key != "" && p.nonce != "" && p.signature != ""
+}
+
+// hmacMessage returns the signed message: method, request uri,
+// timestamp, nonce, name:value of headers and the body hash
+// separated by new lines
+func hmacMessage(method string, uri []byte, p hmacParams, values []string, body []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString(strings.ToUpper(method) + "\n")
+	b.Write(uri)
+	b.WriteString("\n" + p.ts + "\n" + p.nonce + "\n")
+	for i, h := range p.headers {
+		b.WriteString(h + ":" + strings.TrimSpace(values[i]) + "\n")
+	}
+	sum := sha256.Sum256(body)
+	b.WriteString(hex.EncodeToString(sum[:]))
+	return b.Bytes()
+}
+
+func hmacSum(secret []byte, msg []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write(msg)
+	return h.Sum(nil)
+}
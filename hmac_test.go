@@ -0,0 +1,121 @@
+package jsonapi
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestHMAC(t *testing.T) {
+	suite.Run(t, new(HMACTestSuite))
+}
+
+type HMACTestSuite struct {
+	suite.Suite
+	ln     *fasthttputil.InmemoryListener
+	auth   *HMACAuth
+	secret []byte
+}
+
+func (t *HMACTestSuite) SetupTest() {
+	t.secret = []byte("secret")
+	t.auth = NewHMACAuth(map[string][]byte{"billing": t.secret}, HMACRequireHeaders("X-Request-ID"))
+	t.ln = fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(t.ln).SetAuthenticator(t.auth).
+		Get("/me", func(ctx *Ctx) { ctx.OK(ctx.Subject() + ":" + ctx.HMACKeyID()) }).
+		Post("/echo", func(ctx *Ctx) { ctx.OK(string(ctx.PostBody())) })
+	go s.Listen()
+}
+
+func (t *HMACTestSuite) TearDownTest() {
+	t.ln.Close()
+}
+
+func (t *HMACTestSuite) client(secret []byte, headers ...string) *Client {
+	return NewClient(t.ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return t.ln.Dial() }).
+		SetSignFunc(HMACSigner("billing", secret, headers...))
+}
+
+func (t *HMACTestSuite) TestSigned() {
+	cl := t.client(t.secret, "X-Request-ID")
+	rs, err := cl.Get("/me?a=1&b=2")
+	t.NoError(err)
+	t.Equal(StatusOK, rs.StatusCode())
+	t.Equal(`"billing:billing"`, string(rs.Body()))
+
+	rs, err = cl.Post("/echo", "body")
+	t.NoError(err)
+	t.Equal(StatusOK, rs.StatusCode())
+	t.Equal(`"\"body\""`, string(rs.Body()))
+}
+
+func (t *HMACTestSuite) TestRejected() {
+	// wrong secret
+	rs, err := t.client([]byte("other"), "X-Request-ID").Get("/me")
+	t.NoError(err)
+	t.Equal(StatusUnauthorized, rs.StatusCode())
+	t.Equal(HMACScheme, string(rs.Header.Peek("WWW-Authenticate")))
+
+	// required header is not signed
+	rs, err = t.client(t.secret).Get("/me")
+	t.NoError(err)
+	t.Equal(StatusUnauthorized, rs.StatusCode())
+
+	// not signed
+	rs, err = NewClient(t.ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return t.ln.Dial() }).Get("/me")
+	t.NoError(err)
+	t.Equal(StatusUnauthorized, rs.StatusCode())
+
+	// tampered body
+	var auth string
+	cl := t.client(t.secret, "X-Request-ID")
+	cl.SetSignFunc(func(r *Request) {
+		HMACSigner("billing", t.secret, "X-Request-ID")(r)
+		auth = r.Header("Authorization")
+		r.SetBody([]byte(`"other"`))
+	})
+	rs, err = cl.Post("/echo", "body")
+	t.NoError(err)
+	t.Equal(StatusUnauthorized, rs.StatusCode())
+	t.True(strings.HasPrefix(auth, HMACScheme+" key=billing,ts="))
+	t.Equal(ErrInvalidSignature.Error(), rs.Error().(*Error).Err)
+}
+
+func (t *HMACTestSuite) TestReplayAndSkew() {
+	var auth string
+	cl := t.client(t.secret, "X-Request-ID")
+	cl.SetSignFunc(func(r *Request) {
+		if auth == "" {
+			HMACSigner("billing", t.secret, "X-Request-ID")(r)
+			auth = r.Header("Authorization")
+		}
+		r.SetHeader("Authorization", auth)
+	})
+	rs, err := cl.Get("/me")
+	t.NoError(err)
+	t.Equal(StatusOK, rs.StatusCode())
+	rs, err = cl.Get("/me")
+	t.NoError(err)
+	t.Equal(StatusUnauthorized, rs.StatusCode())
+	t.Equal(ErrReplayedRequest.Error(), rs.Error().(*Error).Err)
+
+	t.auth.now = func() time.Time { return time.Now().Add(DefaultHMACSkew + time.Second) }
+	rs, err = t.client(t.secret, "X-Request-ID").Get("/me")
+	t.NoError(err)
+	t.Equal(StatusUnauthorized, rs.StatusCode())
+	t.Equal(ErrSignatureExpired.Error(), rs.Error().(*Error).Err)
+}
+
+func (t *HMACTestSuite) TestNonceCache() {
+	c := NewMemoryNonceCache()
+	t.True(c.Add("a", time.Now().Add(time.Minute)))
+	t.False(c.Add("a", time.Now().Add(time.Minute)))
+	t.True(c.Add("b", time.Now().Add(-time.Second)))
+	t.True(c.Add("b", time.Now().Add(time.Minute)))
+}
@@ -0,0 +1,77 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// Encoder is used to marshal and unmarshal values that
+// don't implement json.Marshaler or json.Unmarshaler
+// (for example: plain structs without easyjson code)
+type Encoder interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// StdEncoder is an Encoder based on encoding/json
+type StdEncoder struct{}
+
+// Marshal implements Encoder
+func (StdEncoder) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements Encoder
+func (StdEncoder) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+var (
+	encoder   Encoder = StdEncoder{}
+	encoderMu sync.RWMutex
+)
+
+// SetEncoder sets the reflection based Encoder used for values
+// that don't implement json.Marshaler or json.Unmarshaler
+func SetEncoder(e Encoder) {
+	if e == nil {
+		e = StdEncoder{}
+	}
+	encoderMu.Lock()
+	encoder = e
+	encoderMu.Unlock()
+}
+
+// GetEncoder returns the current reflection based Encoder
+func GetEncoder() Encoder {
+	encoderMu.RLock()
+	e := encoder
+	encoderMu.RUnlock()
+	return e
+}
+
+// Marshal returns json encoding of v. v may be a json.Marshaler or
+// any value supported by the Encoder. Values implementing
+// json.Marshaler (easyjson types) are marshaled directly,
+// everything else is passed to the current Encoder.
+// nil pointers are encoded as null like encoding/json does
+func Marshal(v interface{}) ([]byte, error) {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return []byte("null"), nil
+	}
+	if m, ok := v.(json.Marshaler); ok {
+		return m.MarshalJSON()
+	}
+	return GetEncoder().Marshal(v)
+}
+
+// Unmarshal parses json data into v. Values implementing
+// json.Unmarshaler (easyjson types) are unmarshaled directly,
+// everything else is passed to the current Encoder
+func Unmarshal(data []byte, v interface{}) error {
+	if u, ok := v.(json.Unmarshaler); ok {
+		return u.UnmarshalJSON(data)
+	}
+	return GetEncoder().Unmarshal(data, v)
+}
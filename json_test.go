@@ -0,0 +1,81 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestJSON(t *testing.T) {
+	suite.Run(t, new(JSONTestSuite))
+}
+
+type JSONTestSuite struct {
+	suite.Suite
+}
+
+type plainStruct struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type upperEncoder struct {
+	StdEncoder
+}
+
+func (upperEncoder) Marshal(v interface{}) ([]byte, error) {
+	return []byte(`"custom"`), nil
+}
+
+type valueMarshaler struct{}
+
+func (valueMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`"value"`), nil
+}
+
+func (t *JSONTestSuite) TestMarshal() {
+	b, err := Marshal(&Error{Err: "some error"})
+	t.NoError(err)
+	t.Equal([]byte(`{"error":"some error"}`), b)
+	b, err = Marshal(plainStruct{1, "Peter"})
+	t.NoError(err)
+	t.Equal([]byte(`{"id":1,"name":"Peter"}`), b)
+	b, err = Marshal(map[string]int{"a": 1})
+	t.NoError(err)
+	t.Equal([]byte(`{"a":1}`), b)
+	_, err = Marshal(make(chan int))
+	t.Error(err)
+	b, err = Marshal((*valueMarshaler)(nil))
+	t.NoError(err)
+	t.Equal([]byte(`null`), b)
+	b, err = Marshal((*Error)(nil))
+	t.NoError(err)
+	t.Equal([]byte(`null`), b)
+}
+
+func (t *JSONTestSuite) TestUnmarshal() {
+	e := new(Error)
+	t.NoError(Unmarshal([]byte(`{"error":"big error","code":1}`), e))
+	t.Equal("big error", e.Err)
+	p := new(plainStruct)
+	t.NoError(Unmarshal([]byte(`{"id":2,"name":"Tom"}`), p))
+	t.Equal(&plainStruct{2, "Tom"}, p)
+	t.Error(Unmarshal([]byte(`{`), p))
+}
+
+func (t *JSONTestSuite) TestSetEncoder() {
+	defer SetEncoder(nil)
+	t.IsType(StdEncoder{}, GetEncoder())
+	SetEncoder(upperEncoder{})
+	t.IsType(upperEncoder{}, GetEncoder())
+	b, err := Marshal(plainStruct{})
+	t.NoError(err)
+	t.Equal([]byte(`"custom"`), b)
+	// json.Marshaler values never reach the encoder
+	b, err = Marshal(json.RawMessage(`{"raw":true}`))
+	t.NoError(err)
+	t.Equal([]byte(`{"raw":true}`), b)
+	SetEncoder(nil)
+	t.IsType(StdEncoder{}, GetEncoder())
+}
@@ -0,0 +1,363 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mailru/easyjson"
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// DefaultRPCPath is the default path used by Client.Call
+	DefaultRPCPath = "/rpc"
+
+	// RPCVersion is the supported json-rpc version
+	RPCVersion = "2.0"
+
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCInternalError  = -32603
+	RPCServerError    = -32000
+)
+
+// RPCHandler defines json-rpc method handler func.
+// The returned value is encoded as the call result.
+// A returned *RPCError is sent as is, other errors are mapped
+// by server error mappers like controller errors and converted
+// by NewRPCError, unmapped errors are RPCInternalError
+type RPCHandler func(*Ctx, RPCParams) (interface{}, error)
+
+// RPCParams are raw json params of a json-rpc call
+type RPCParams []byte
+
+// ReadJSON reads params into v or returns *RPCError
+// with RPCInvalidParams code
+func (p RPCParams) ReadJSON(v interface{}) error {
+	if len(p) == 0 {
+		return nil
+	}
+	if err := Unmarshal(p, v); err != nil {
+		return &RPCError{Code: RPCInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// RPCRequest is a json-rpc request object
+//
+//easyjson:json
+type RPCRequest struct {
+	JSONRPC string              `json:"jsonrpc"`
+	Method  string              `json:"method"`
+	Params  easyjson.RawMessage `json:"params,omitempty"`
+	ID      easyjson.RawMessage `json:"id,omitempty"`
+
+	hasID bool // id member is present, its value may be null
+}
+
+// IsNotification returns TRUE if the request has no id
+// and doesn't expect a response
+func (r *RPCRequest) IsNotification() bool {
+	return len(r.ID) == 0 && !r.hasID
+}
+
+// decodeRPCRequest decodes a request of data, the generated decoder
+// skips null members so presence of the id is checked separately
+func decodeRPCRequest(data []byte) (*RPCRequest, error) {
+	req := new(RPCRequest)
+	if err := req.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	in := jlexer.Lexer{Data: data}
+	in.Delim('{')
+	for in.Ok() && !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if key == "id" {
+			req.hasID = true
+			break
+		}
+		in.SkipRecursive()
+		in.WantComma()
+	}
+	return req, nil
+}
+
+// RPCResponse is a json-rpc response object
+//
+//easyjson:json
+type RPCResponse struct {
+	JSONRPC string              `json:"jsonrpc"`
+	Result  easyjson.RawMessage `json:"result,omitempty"`
+	Error   *RPCError           `json:"error,omitempty"`
+	ID      easyjson.RawMessage `json:"id"`
+}
+
+// RPCError is a json-rpc error object
+//
+//easyjson:json
+type RPCError struct {
+	Code    int                 `json:"code"`
+	Message string              `json:"message"`
+	Data    easyjson.RawMessage `json:"data,omitempty"`
+}
+
+// Error implements error interface
+func (e *RPCError) Error() string {
+	return strconv.Itoa(e.Code) + ": " + e.Message
+}
+
+// NewRPCError converts err into *RPCError, *RPCError and *Error found
+// in err chain are converted by code, other errors are RPCInternalError
+// without the error message
+func NewRPCError(err error) *RPCError {
+	var re *RPCError
+	if errors.As(err, &re) {
+		return re
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return rpcErrorFromError(e)
+	}
+	var ev Error
+	if errors.As(err, &ev) {
+		return rpcErrorFromError(&ev)
+	}
+	return &RPCError{Code: RPCInternalError, Message: "internal error"}
+}
+
+// rpcError converts a handler error of ctx into *RPCError,
+// errors are mapped by s like controller errors
+func rpcError(s *Server, ctx *Ctx, err error) *RPCError {
+	var re *RPCError
+	if errors.As(err, &re) {
+		return re
+	}
+	return NewRPCError(s.requestError(ctx, err))
+}
+
+func rpcErrorFromError(e *Error) *RPCError {
+	re := &RPCError{Code: RPCServerError, Message: e.Err}
+	switch e.Code {
+	case StatusBadRequest:
+		re.Code = RPCInvalidParams
+	case StatusInternalServerError:
+		re.Code = RPCInternalError
+		if e.Err == ErrInternal.Error() {
+			re.Message = "internal error"
+		}
+	}
+	// keep the original error, including http code, in data
+	re.Data, _ = e.MarshalJSON()
+	return re
+}
+
+// NewRPCRegistry creates a new json-rpc method registry
+func NewRPCRegistry() *RPCRegistry {
+	return &RPCRegistry{
+		methods: map[string]RPCHandler{},
+		mu:      new(sync.RWMutex),
+	}
+}
+
+// RPCRegistry holds json-rpc methods
+type RPCRegistry struct {
+	methods map[string]RPCHandler
+	mu      *sync.RWMutex
+}
+
+// Register registers a json-rpc method handler
+func (r *RPCRegistry) Register(method string, handler RPCHandler) *RPCRegistry {
+	r.mu.Lock()
+	r.methods[method] = handler
+	r.mu.Unlock()
+	return r
+}
+
+// Methods returns sorted names of registered methods
+func (r *RPCRegistry) Methods() []string {
+	r.mu.RLock()
+	res := make([]string, 0, len(r.methods))
+	for m := range r.methods {
+		res = append(res, m)
+	}
+	r.mu.RUnlock()
+	sort.Strings(res)
+	return res
+}
+
+func (r *RPCRegistry) lookup(method string) (RPCHandler, bool) {
+	r.mu.RLock()
+	h, ok := r.methods[method]
+	r.mu.RUnlock()
+	return h, ok
+}
+
+// call executes a single request, nil is returned for notifications
+func (r *RPCRegistry) call(s *Server, ctx *Ctx, req *RPCRequest) *RPCResponse {
+	res := &RPCResponse{JSONRPC: RPCVersion, ID: req.ID}
+	if req.JSONRPC != RPCVersion || req.Method == "" {
+		res.Error = &RPCError{Code: RPCInvalidRequest, Message: "invalid request"}
+		return res
+	}
+	h, ok := r.lookup(req.Method)
+	if !ok {
+		res.Error = &RPCError{Code: RPCMethodNotFound, Message: "method not found"}
+	} else if v, err := h(ctx, RPCParams(req.Params)); err != nil {
+		res.Error = rpcError(s, ctx, err)
+	} else if res.Result, err = Marshal(v); err != nil {
+		res.Error = rpcError(s, ctx, err)
+	}
+	if req.IsNotification() {
+		return nil
+	}
+	return res
+}
+
+// ServeRPC handles a json-rpc request or batch from ctx body,
+// handler errors are not mapped by server error mappers unless
+// the registry is registered by Server.JSONRPC
+func (r *RPCRegistry) ServeRPC(ctx *Ctx) {
+	r.serve(nil, ctx)
+}
+
+func (r *RPCRegistry) serve(s *Server, ctx *Ctx) {
+	body := bytes.TrimSpace(ctx.PostBody())
+	if len(body) > 0 && body[0] == '[' {
+		r.serveBatch(s, ctx, body)
+		return
+	}
+	req, err := decodeRPCRequest(body)
+	if err != nil {
+		e := &RPCError{Code: RPCParseError, Message: err.Error()}
+		if json.Valid(body) {
+			// valid json, but not a request object
+			e = &RPCError{Code: RPCInvalidRequest, Message: "invalid request"}
+		}
+		ctx.OK(&RPCResponse{JSONRPC: RPCVersion, Error: e})
+		return
+	}
+	res := r.call(s, ctx, req)
+	if res == nil {
+		ctx.SetStatusCode(fasthttp.StatusNoContent)
+		return
+	}
+	ctx.OK(res)
+}
+
+func (r *RPCRegistry) serveBatch(s *Server, ctx *Ctx, body []byte) {
+	var reqs []easyjson.RawMessage
+	if err := GetEncoder().Unmarshal(body, &reqs); err != nil {
+		ctx.OK(&RPCResponse{
+			JSONRPC: RPCVersion,
+			Error:   &RPCError{Code: RPCParseError, Message: err.Error()},
+		})
+		return
+	}
+	if len(reqs) == 0 {
+		ctx.OK(&RPCResponse{
+			JSONRPC: RPCVersion,
+			Error:   &RPCError{Code: RPCInvalidRequest, Message: "empty batch"},
+		})
+		return
+	}
+	var res []*RPCResponse
+	for _, raw := range reqs {
+		req, err := decodeRPCRequest(raw)
+		if err != nil {
+			res = append(res, &RPCResponse{
+				JSONRPC: RPCVersion,
+				Error:   &RPCError{Code: RPCInvalidRequest, Message: "invalid request"},
+			})
+			continue
+		}
+		if rs := r.call(s, ctx, req); rs != nil {
+			res = append(res, rs)
+		}
+	}
+	if len(res) == 0 {
+		// batch of notifications
+		ctx.SetStatusCode(fasthttp.StatusNoContent)
+		return
+	}
+	ctx.OK(res)
+}
+
+// JSONRPC registers a json-rpc 2.0 endpoint on path
+func (s *Server) JSONRPC(p string, registry *RPCRegistry, opts ...RouteOption) *Server {
+	return s.Post(p, func(ctx *Ctx) {
+		registry.serve(s, ctx)
+	}, append([]RouteOption{noBatch(ErrBatchUnsupported)}, opts...)...)
+}
+
+// SetRPCPath sets the path used by Call and Notify
+func (c *Client) SetRPCPath(p string) *Client {
+	c.rpcPath = p
+	return c
+}
+
+// Call makes a json-rpc call of method with params and reads
+// the call result into result. Errors returned by the server
+// are returned as *RPCError
+func (c *Client) Call(method string, params interface{}, result interface{}) error {
+	id := strconv.FormatUint(atomic.AddUint64(&c.rpcID, 1), 10)
+	rs, err := c.rpc(method, params, id)
+	if err != nil {
+		return err
+	}
+	if rs.StatusCode() != StatusOK {
+		return rs.Error()
+	}
+	res := new(RPCResponse)
+	if err := rs.ReadJSON(res); err != nil {
+		return err
+	}
+	if res.Error != nil {
+		return res.Error
+	}
+	// an empty or null result is not decoded
+	if result == nil || len(res.Result) == 0 || string(res.Result) == "null" {
+		return nil
+	}
+	return Unmarshal(res.Result, result)
+}
+
+// Notify sends a json-rpc notification, the server
+// doesn't return any result for notifications
+func (c *Client) Notify(method string, params interface{}) error {
+	rs, err := c.rpc(method, params, "")
+	if err != nil {
+		return err
+	}
+	if rs.StatusCode() >= StatusBadRequest {
+		return rs.Error()
+	}
+	return nil
+}
+
+func (c *Client) rpc(method string, params interface{}, id string) (*Response, error) {
+	req := &RPCRequest{JSONRPC: RPCVersion, Method: method}
+	if params != nil {
+		b, err := Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		req.Params = b
+	}
+	if id != "" {
+		req.ID = easyjson.RawMessage(id)
+	}
+	p := c.rpcPath
+	if p == "" {
+		p = DefaultRPCPath
+	}
+	return c.Post(p, req)
+}
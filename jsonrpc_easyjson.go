@@ -0,0 +1,281 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package jsonapi
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjsonE7d0658dDecodeGithubComSkamenetskiyJsonapi(in *jlexer.Lexer, out *RPCResponse) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "jsonrpc":
+			out.JSONRPC = string(in.String())
+		case "result":
+			(out.Result).UnmarshalEasyJSON(in)
+		case "error":
+			if in.IsNull() {
+				in.Skip()
+				out.Error = nil
+			} else {
+				if out.Error == nil {
+					out.Error = new(RPCError)
+				}
+				(*out.Error).UnmarshalEasyJSON(in)
+			}
+		case "id":
+			(out.ID).UnmarshalEasyJSON(in)
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonE7d0658dEncodeGithubComSkamenetskiyJsonapi(out *jwriter.Writer, in RPCResponse) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"jsonrpc\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.JSONRPC))
+	}
+	if (in.Result).IsDefined() {
+		const prefix string = ",\"result\":"
+		out.RawString(prefix)
+		(in.Result).MarshalEasyJSON(out)
+	}
+	if in.Error != nil {
+		const prefix string = ",\"error\":"
+		out.RawString(prefix)
+		(*in.Error).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"id\":"
+		out.RawString(prefix)
+		(in.ID).MarshalEasyJSON(out)
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v RPCResponse) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonE7d0658dEncodeGithubComSkamenetskiyJsonapi(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v RPCResponse) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonE7d0658dEncodeGithubComSkamenetskiyJsonapi(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *RPCResponse) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonE7d0658dDecodeGithubComSkamenetskiyJsonapi(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *RPCResponse) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonE7d0658dDecodeGithubComSkamenetskiyJsonapi(l, v)
+}
+func easyjsonE7d0658dDecodeGithubComSkamenetskiyJsonapi1(in *jlexer.Lexer, out *RPCRequest) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "jsonrpc":
+			out.JSONRPC = string(in.String())
+		case "method":
+			out.Method = string(in.String())
+		case "params":
+			(out.Params).UnmarshalEasyJSON(in)
+		case "id":
+			(out.ID).UnmarshalEasyJSON(in)
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonE7d0658dEncodeGithubComSkamenetskiyJsonapi1(out *jwriter.Writer, in RPCRequest) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"jsonrpc\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.JSONRPC))
+	}
+	{
+		const prefix string = ",\"method\":"
+		out.RawString(prefix)
+		out.String(string(in.Method))
+	}
+	if (in.Params).IsDefined() {
+		const prefix string = ",\"params\":"
+		out.RawString(prefix)
+		(in.Params).MarshalEasyJSON(out)
+	}
+	if (in.ID).IsDefined() {
+		const prefix string = ",\"id\":"
+		out.RawString(prefix)
+		(in.ID).MarshalEasyJSON(out)
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v RPCRequest) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonE7d0658dEncodeGithubComSkamenetskiyJsonapi1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v RPCRequest) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonE7d0658dEncodeGithubComSkamenetskiyJsonapi1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *RPCRequest) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonE7d0658dDecodeGithubComSkamenetskiyJsonapi1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *RPCRequest) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonE7d0658dDecodeGithubComSkamenetskiyJsonapi1(l, v)
+}
+func easyjsonE7d0658dDecodeGithubComSkamenetskiyJsonapi2(in *jlexer.Lexer, out *RPCError) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "code":
+			out.Code = int(in.Int())
+		case "message":
+			out.Message = string(in.String())
+		case "data":
+			(out.Data).UnmarshalEasyJSON(in)
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonE7d0658dEncodeGithubComSkamenetskiyJsonapi2(out *jwriter.Writer, in RPCError) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"code\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.Code))
+	}
+	{
+		const prefix string = ",\"message\":"
+		out.RawString(prefix)
+		out.String(string(in.Message))
+	}
+	if (in.Data).IsDefined() {
+		const prefix string = ",\"data\":"
+		out.RawString(prefix)
+		(in.Data).MarshalEasyJSON(out)
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v RPCError) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonE7d0658dEncodeGithubComSkamenetskiyJsonapi2(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v RPCError) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonE7d0658dEncodeGithubComSkamenetskiyJsonapi2(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *RPCError) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonE7d0658dDecodeGithubComSkamenetskiyJsonapi2(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *RPCError) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonE7d0658dDecodeGithubComSkamenetskiyJsonapi2(l, v)
+}
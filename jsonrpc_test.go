@@ -0,0 +1,168 @@
+package jsonapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestJSONRPC(t *testing.T) {
+	suite.Run(t, new(JSONRPCTestSuite))
+}
+
+type JSONRPCTestSuite struct {
+	suite.Suite
+	ln       *fasthttputil.InmemoryListener
+	client   *Client
+	notified chan string
+}
+
+var errRPCMissing = errors.New("missing")
+
+func (t *JSONRPCTestSuite) SetupTest() {
+	t.notified = make(chan string, 1)
+	reg := NewRPCRegistry().
+		Register("echo", func(ctx *Ctx, params RPCParams) (interface{}, error) {
+			p := new(plainStruct)
+			if err := params.ReadJSON(p); err != nil {
+				return nil, err
+			}
+			return p, nil
+		}).
+		Register("notFound", func(*Ctx, RPCParams) (interface{}, error) {
+			return nil, NewErrorString("animal not found", StatusNotFound)
+		}).
+		Register("fail", func(*Ctx, RPCParams) (interface{}, error) {
+			return nil, errors.New("fail")
+		}).
+		Register("wrapped", func(*Ctx, RPCParams) (interface{}, error) {
+			return nil, fmt.Errorf("load: %w", NewErrorString("animal not found", StatusNotFound))
+		}).
+		Register("mapped", func(*Ctx, RPCParams) (interface{}, error) {
+			return nil, fmt.Errorf("load: %w", errRPCMissing)
+		}).
+		Register("notify", func(ctx *Ctx, params RPCParams) (interface{}, error) {
+			var s string
+			params.ReadJSON(&s)
+			t.notified <- s
+			return nil, nil
+		})
+	var s *Server
+	t.ln, s = new(ServerTestSuite).getServer()
+	s.MapError(errRPCMissing, StatusNotFound)
+	s.JSONRPC(DefaultRPCPath, reg)
+	go s.Listen()
+	t.client = NewClient("rpc.local").SetDialFunc(func(string) (net.Conn, error) {
+		return t.ln.Dial()
+	})
+}
+
+func (t *JSONRPCTestSuite) TearDownTest() {
+	t.ln.Close()
+}
+
+func (t *JSONRPCTestSuite) post(body string) string {
+	rs, err := t.client.Post(DefaultRPCPath, BytesResult(body))
+	t.NoError(err)
+	return string(rs.Body())
+}
+
+func (t *JSONRPCTestSuite) TestCall() {
+	res := new(plainStruct)
+	t.NoError(t.client.Call("echo", plainStruct{1, "Peter"}, res))
+	t.Equal(&plainStruct{1, "Peter"}, res)
+	t.NoError(t.client.Call("notify", "a", nil))
+	t.Equal("a", <-t.notified)
+	// null results are not decoded
+	t.NoError(t.client.Call("notify", "b", res))
+	t.Equal("b", <-t.notified)
+	t.Equal(&plainStruct{1, "Peter"}, res)
+}
+
+func (t *JSONRPCTestSuite) TestCallErrors() {
+	err := t.client.Call("missing", nil, nil)
+	t.Equal(&RPCError{Code: RPCMethodNotFound, Message: "method not found"}, err)
+	err = t.client.Call("echo", "not an object", nil)
+	t.IsType(&RPCError{}, err)
+	t.Equal(RPCInvalidParams, err.(*RPCError).Code)
+	c := t.client.WithContext(ContextWithRequestID(context.Background(), "rpc-1"))
+	for _, method := range []string{"notFound", "wrapped"} {
+		err = c.Call(method, nil, nil)
+		t.Equal(&RPCError{
+			Code:    RPCServerError,
+			Message: "animal not found",
+			Data:    []byte(`{"error":"animal not found","code":404,"requestId":"rpc-1"}`),
+		}, err, method)
+	}
+	err = c.Call("mapped", nil, nil)
+	t.Equal(&RPCError{
+		Code:    RPCServerError,
+		Message: "load: missing",
+		Data:    []byte(`{"error":"load: missing","code":404,"requestId":"rpc-1"}`),
+	}, err)
+	// unmapped errors are not sent
+	err = c.Call("fail", nil, nil)
+	t.Equal(&RPCError{
+		Code:    RPCInternalError,
+		Message: "internal error",
+		Data:    []byte(`{"error":"internal server error","code":500,"requestId":"rpc-1"}`),
+	}, err)
+	t.Equal(&RPCError{Code: RPCInternalError, Message: "internal error"}, NewRPCError(errors.New("fail")))
+}
+
+func (t *JSONRPCTestSuite) TestNotify() {
+	t.NoError(t.client.Notify("notify", "b"))
+	t.Equal("b", <-t.notified)
+	t.Equal("", t.post(`{"jsonrpc":"2.0","method":"missing"}`))
+	// a null id is not a notification
+	t.Equal(`{"jsonrpc":"2.0","result":null,"id":null}`,
+		t.post(`{"jsonrpc":"2.0","method":"notify","params":"c","id":null}`))
+	t.Equal("c", <-t.notified)
+}
+
+func (t *JSONRPCTestSuite) TestBatch() {
+	body := t.post(`[
+		{"jsonrpc":"2.0","method":"echo","params":{"id":1},"id":1},
+		{"jsonrpc":"2.0","method":"notify","params":"c"},
+		{"jsonrpc":"2.0","method":"missing","id":"x"},
+		1
+	]`)
+	t.Equal(`[{"jsonrpc":"2.0","result":{"id":1,"name":""},"id":1},`+
+		`{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not found"},"id":"x"},`+
+		`{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request"},"id":null}]`, body)
+	t.Equal("c", <-t.notified)
+	t.Equal("", t.post(`[{"jsonrpc":"2.0","method":"notify","params":"d"}]`))
+	t.Equal("d", <-t.notified)
+	t.Equal(`{"jsonrpc":"2.0","error":{"code":-32600,"message":"empty batch"},"id":null}`, t.post(`[]`))
+}
+
+func (t *JSONRPCTestSuite) TestInvalid() {
+	t.Contains(t.post(`{`), `"code":-32700`)
+	t.Contains(t.post(`[{]`), `"code":-32700`)
+	// valid json that isn't a request object
+	for _, body := range []string{`1`, `"x"`, `{"jsonrpc":"2.0","method":1,"id":1}`} {
+		t.Equal(`{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request"},"id":null}`, t.post(body), body)
+	}
+	t.Equal(`{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request"},"id":1}`,
+		t.post(`{"jsonrpc":"1.0","method":"echo","id":1}`))
+}
+
+func (t *JSONRPCTestSuite) TestCallUnauthorized() {
+	ln, s := new(ServerTestSuite).getServer()
+	s.SetAuthFunc(func(*Ctx) bool { return false })
+	s.JSONRPC("/api/rpc", NewRPCRegistry())
+	go s.Listen()
+	defer ln.Close()
+	c := NewClient("rpc.local").SetRPCPath("/api/rpc").SetDialFunc(func(string) (net.Conn, error) {
+		return ln.Dial()
+	})
+	err := c.Call("echo", nil, nil)
+	t.IsType(&Error{}, err)
+	t.Equal(StatusUnauthorized, err.(*Error).Code)
+	t.Error(c.Notify("echo", nil))
+}
@@ -0,0 +1,544 @@
+package jsonapi
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	JWTAlgHS256 = "HS256"
+	JWTAlgRS256 = "RS256"
+	JWTAlgES256 = "ES256"
+
+	claimsKey = "jsonapi.claims"
+)
+
+var (
+	ErrInvalidToken      = errors.New("invalid token")
+	ErrTokenExpired      = errors.New("token is expired")
+	ErrTokenNotYetValid  = errors.New("token is not valid yet")
+	ErrTokenIssuer       = errors.New("invalid token issuer")
+	ErrTokenAudience     = errors.New("invalid token audience")
+	ErrTokenAlgorithm    = errors.New("unsupported token algorithm")
+	ErrTokenKeyNotFound  = errors.New("token key not found")
+	ErrTokenSignature    = errors.New("invalid token signature")
+	ErrUnsupportedJWTKey = errors.New("unsupported jwt key")
+)
+
+// Claims are JWT claims
+type Claims map[string]interface{}
+
+// Subject returns the sub claim
+func (c Claims) Subject() string {
+	s, _ := c["sub"].(string)
+	return s
+}
+
+// Issuer returns the iss claim
+func (c Claims) Issuer() string {
+	s, _ := c["iss"].(string)
+	return s
+}
+
+// Audience returns the aud claim, which may be a string or a list
+func (c Claims) Audience() []string {
+	return c.strings("aud")
+}
+
+// Scopes returns scopes of the space separated scope claim or the scp list
+func (c Claims) Scopes() []string {
+	if s, ok := c["scope"].(string); ok {
+		return strings.Fields(s)
+	}
+	return c.strings("scp")
+}
+
+// strings returns a string or a list of strings claim as a list
+func (c Claims) strings(k string) []string {
+	switch v := c[k].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		res := make([]string, 0, len(v))
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+// ExpiresAt returns the exp claim or zero time
+func (c Claims) ExpiresAt() time.Time {
+	t, _ := c.time("exp")
+	return t
+}
+
+// NotBefore returns the nbf claim or zero time
+func (c Claims) NotBefore() time.Time {
+	t, _ := c.time("nbf")
+	return t
+}
+
+// time returns the NumericDate claim k, it returns FALSE
+// if the claim is present but not a number
+func (c Claims) time(k string) (time.Time, bool) {
+	var secs float64
+	switch v := c[k].(type) {
+	case nil:
+		return time.Time{}, true
+	case float64:
+		secs = v
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		secs = f
+	case int64:
+		secs = float64(v)
+	case int:
+		secs = float64(v)
+	default:
+		return time.Time{}, false
+	}
+	if math.IsNaN(secs) || math.IsInf(secs, 0) {
+		return time.Time{}, false
+	}
+	// NumericDate may have a fraction of a second
+	whole, frac := math.Modf(secs)
+	return time.Unix(int64(whole), int64(frac*1e9)), true
+}
+
+// JWTOption configures JWTAuth
+type JWTOption func(*JWTAuth) error
+
+// JWTKey adds a verification key with key id kid ("" for tokens
+// without kid). key is []byte for HS256, *rsa.PublicKey for RS256
+// and *ecdsa.PublicKey for ES256
+func JWTKey(kid string, key interface{}) JWTOption {
+	return func(a *JWTAuth) error {
+		if jwtKeyAlg(key) == "" {
+			return ErrUnsupportedJWTKey
+		}
+		a.keys[kid] = key
+		return nil
+	}
+}
+
+// JWTKeysFile adds verification keys from a local JWKS file
+func JWTKeysFile(path string) JWTOption {
+	return func(a *JWTAuth) error {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		keys, err := ParseJWKS(b)
+		if err != nil {
+			return err
+		}
+		for kid, key := range keys {
+			a.keys[kid] = key
+		}
+		return nil
+	}
+}
+
+// JWTIssuer requires the iss claim to be issuer
+func JWTIssuer(issuer string) JWTOption {
+	return func(a *JWTAuth) error {
+		a.issuer = issuer
+		return nil
+	}
+}
+
+// JWTAudience requires the aud claim to contain audience
+func JWTAudience(audience string) JWTOption {
+	return func(a *JWTAuth) error {
+		a.audience = audience
+		return nil
+	}
+}
+
+// JWTLeeway sets the allowed clock skew for exp and nbf checks
+func JWTLeeway(d time.Duration) JWTOption {
+	return func(a *JWTAuth) error {
+		a.leeway = d
+		return nil
+	}
+}
+
+// JWTRequireExpiry rejects tokens without the exp claim
+func JWTRequireExpiry() JWTOption {
+	return func(a *JWTAuth) error {
+		a.requireExp = true
+		return nil
+	}
+}
+
+// NewJWTAuth creates a JWT bearer token authenticator
+func NewJWTAuth(opts ...JWTOption) (*JWTAuth, error) {
+	a := &JWTAuth{keys: map[string]interface{}{}, now: time.Now}
+	for _, opt := range opts {
+		if err := opt(a); err != nil {
+			return nil, err
+		}
+	}
+	if len(a.keys) == 0 {
+		return nil, ErrTokenKeyNotFound
+	}
+	return a, nil
+}
+
+// JWTAuth verifies JWT bearer tokens signed
+// with HS256, RS256 or ES256
+type JWTAuth struct {
+	keys       map[string]interface{} // by kid
+	issuer     string
+	audience   string
+	leeway     time.Duration
+	requireExp bool
+	now        func() time.Time
+}
+
+// Authenticate implements Authenticator, it verifies the bearer token
+// of the Authorization header and returns a principal with the sub
+// claim as subject, roles of the roles claim and scopes of the scope
+// (space separated) or scp claim. Verified claims are stored on Ctx
+func (a *JWTAuth) Authenticate(ctx *Ctx) (*Principal, error) {
+	token, ok := BearerToken(ctx)
+	if !ok {
+		return nil, AuthChallenge(ErrUnauthorized, "Bearer")
+	}
+	claims, err := a.Verify(token)
+	if err != nil {
+		return nil, AuthChallenge(err, `Bearer error="invalid_token"`)
+	}
+	ctx.SetUserValue(claimsKey, claims)
+	return &Principal{
+		Subject: claims.Subject(),
+		Method:  "jwt",
+		Roles:   claims.strings("roles"),
+		Scopes:  claims.Scopes(),
+		Claims:  claims,
+	}, nil
+}
+
+// AuthFunc returns a ServerAuthFunc verifying the bearer token of
+// the Authorization header. Verified claims are stored on Ctx and
+// the sub claim is set as the request subject
+func (a *JWTAuth) AuthFunc() ServerAuthFunc {
+	return func(ctx *Ctx) bool {
+		p, err := a.Authenticate(ctx)
+		if err != nil {
+			return false
+		}
+		ctx.SetSubject(p.Subject)
+		return true
+	}
+}
+
+// Verify verifies token signature and claims and returns the claims
+func (a *JWTAuth) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+	key, err := a.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	// the key type decides the algorithm, it prevents algorithm confusion
+	if jwtKeyAlg(key) != header.Alg {
+		return nil, ErrTokenAlgorithm
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !verifyJWT(header.Alg, key, []byte(parts[0]+"."+parts[1]), sig) {
+		return nil, ErrTokenSignature
+	}
+	claims := Claims{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err := a.validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (a *JWTAuth) key(kid string) (interface{}, error) {
+	if key, ok := a.keys[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key, nil
+		}
+	}
+	return nil, ErrTokenKeyNotFound
+}
+
+func (a *JWTAuth) validate(claims Claims) error {
+	now := a.now()
+	exp, ok := claims.time("exp")
+	if !ok {
+		return ErrInvalidToken
+	}
+	nbf, ok := claims.time("nbf")
+	if !ok {
+		return ErrInvalidToken
+	}
+	if _, present := claims["exp"]; present {
+		if now.After(exp.Add(a.leeway)) {
+			return ErrTokenExpired
+		}
+	} else if a.requireExp {
+		return ErrInvalidToken
+	}
+	if _, present := claims["nbf"]; present && now.Add(a.leeway).Before(nbf) {
+		return ErrTokenNotYetValid
+	}
+	if a.issuer != "" && claims.Issuer() != a.issuer {
+		return ErrTokenIssuer
+	}
+	if a.audience != "" {
+		for _, aud := range claims.Audience() {
+			if aud == a.audience {
+				return nil
+			}
+		}
+		return ErrTokenAudience
+	}
+	return nil
+}
+
+// Claims returns JWT claims verified by JWTAuth or nil
+func (c *Ctx) Claims() Claims {
+	claims, _ := c.UserValue(claimsKey).(Claims)
+	return claims
+}
+
+// BearerToken returns the bearer token of the Authorization header
+func BearerToken(ctx *Ctx) (string, bool) {
+	h := ctx.GetHeader("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(h[7:])
+	return token, token != ""
+}
+
+// SignJWT signs claims with key. key is []byte for HS256,
+// *rsa.PrivateKey for RS256 and *ecdsa.PrivateKey for ES256
+func SignJWT(key interface{}, kid string, claims Claims) (string, error) {
+	var alg string
+	switch k := key.(type) {
+	case []byte:
+		alg = JWTAlgHS256
+	case *rsa.PrivateKey:
+		alg = JWTAlgRS256
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return "", ErrUnsupportedJWTKey
+		}
+		alg = JWTAlgES256
+	default:
+		return "", ErrUnsupportedJWTKey
+	}
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	digest := sha256.Sum256([]byte(signed))
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		m := hmac.New(sha256.New, k)
+		m.Write([]byte(signed))
+		sig = m.Sum(nil)
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest[:])
+		if err == nil {
+			sig = make([]byte, 64)
+			r.FillBytes(sig[:32])
+			s.FillBytes(sig[32:])
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// ParseJWKS parses RSA, EC (P-256) and oct keys of a JWKS document by kid
+func ParseJWKS(b []byte) (map[string]interface{}, error) {
+	var doc struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+			K   string `json:"k"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	keys := map[string]interface{}{}
+	for _, k := range doc.Keys {
+		switch k.Kty {
+		case "RSA":
+			n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+			e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+			if err1 != nil || err2 != nil {
+				return nil, ErrUnsupportedJWTKey
+			}
+			keys[k.Kid] = &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}
+		case "EC":
+			x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+			y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+			if k.Crv != "P-256" || err1 != nil || err2 != nil {
+				return nil, ErrUnsupportedJWTKey
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(x),
+				Y:     new(big.Int).SetBytes(y),
+			}
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil || len(secret) == 0 {
+				return nil, ErrUnsupportedJWTKey
+			}
+			keys[k.Kid] = secret
+		default:
+			return nil, ErrUnsupportedJWTKey
+		}
+	}
+	return keys, nil
+}
+
+// TokenSource returns a token and its expiry time,
+// zero expiry time means the token doesn't expire
+type TokenSource func() (token string, expiresAt time.Time, err error)
+
+// BearerAuth returns a ClientAuthFunc attaching tokens of source as
+// bearer tokens. Tokens are cached and refreshed before they expire,
+// if source fails the request is sent without a token
+func BearerAuth(source TokenSource) ClientAuthFunc {
+	var (
+		mu      sync.Mutex
+		token   string
+		expires time.Time
+	)
+	return func(r *Request) {
+		mu.Lock()
+		// refresh tokens expiring within 30 seconds
+		if token == "" || (!expires.IsZero() && time.Now().Add(30*time.Second).After(expires)) {
+			t, exp, err := source()
+			if err == nil {
+				token, expires = t, exp
+			} else {
+				token = ""
+			}
+		}
+		t := token
+		mu.Unlock()
+		if t != "" {
+			r.SetHeader("Authorization", "Bearer "+t)
+		}
+	}
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// jwtKeyAlg returns the algorithm of a verification key
+func jwtKeyAlg(key interface{}) string {
+	switch k := key.(type) {
+	case []byte:
+		// anyone can sign with an empty secret
+		if len(k) > 0 {
+			return JWTAlgHS256
+		}
+	case *rsa.PublicKey:
+		return JWTAlgRS256
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P256() {
+			return JWTAlgES256
+		}
+	}
+	return ""
+}
+
+func verifyJWT(alg string, key interface{}, signed []byte, sig []byte) bool {
+	digest := sha256.Sum256(signed)
+	switch alg {
+	case JWTAlgHS256:
+		m := hmac.New(sha256.New, key.([]byte))
+		m.Write(signed)
+		return hmac.Equal(m.Sum(nil), sig)
+	case JWTAlgRS256:
+		return rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), crypto.SHA256, digest[:], sig) == nil
+	case JWTAlgES256:
+		if len(sig) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(key.(*ecdsa.PublicKey), digest[:], r, s)
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package jsonapi
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestJWT(t *testing.T) {
+	suite.Run(t, new(JWTTestSuite))
+}
+
+type JWTTestSuite struct {
+	suite.Suite
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+	secret []byte
+}
+
+func (t *JWTTestSuite) SetupSuite() {
+	var err error
+	t.rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	t.NoError(err)
+	t.ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	t.NoError(err)
+	t.secret = []byte("secret")
+}
+
+func (t *JWTTestSuite) claims(d time.Duration) Claims {
+	return Claims{
+		"sub": "user-1",
+		"iss": "issuer",
+		"aud": []string{"api", "other"},
+		"exp": time.Now().Add(d).Unix(),
+	}
+}
+
+func (t *JWTTestSuite) sign(key interface{}, kid string, claims Claims) string {
+	token, err := SignJWT(key, kid, claims)
+	t.NoError(err)
+	return token
+}
+
+func (t *JWTTestSuite) TestVerify() {
+	a, err := NewJWTAuth(
+		JWTKey("hs", t.secret),
+		JWTKey("rs", &t.rsaKey.PublicKey),
+		JWTKey("es", &t.ecKey.PublicKey),
+		JWTIssuer("issuer"),
+		JWTAudience("api"),
+		JWTLeeway(time.Minute),
+	)
+	t.NoError(err)
+	for kid, key := range map[string]interface{}{"hs": t.secret, "rs": t.rsaKey, "es": t.ecKey} {
+		claims, err := a.Verify(t.sign(key, kid, t.claims(time.Hour)))
+		t.NoError(err, kid)
+		t.Equal("user-1", claims.Subject())
+		t.Equal([]string{"api", "other"}, claims.Audience())
+	}
+	// within leeway
+	_, err = a.Verify(t.sign(t.secret, "hs", t.claims(-30*time.Second)))
+	t.NoError(err)
+
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	noAud := t.claims(time.Hour)
+	delete(noAud, "aud")
+	wrongIss := t.claims(time.Hour)
+	wrongIss["iss"] = "other"
+	future := t.claims(time.Hour)
+	future["nbf"] = time.Now().Add(time.Hour).Unix()
+	fraction := t.claims(0)
+	fraction["exp"] = 1000.5
+	stringExp := t.claims(0)
+	stringExp["exp"] = "2999-01-01"
+	stringNbf := t.claims(time.Hour)
+	stringNbf["nbf"] = "now"
+	tests := map[string]error{
+		"invalid": ErrInvalidToken,
+		t.sign(t.secret, "hs", t.claims(-time.Hour)):     ErrTokenExpired,
+		t.sign(t.secret, "hs", noAud):                    ErrTokenAudience,
+		t.sign(t.secret, "hs", wrongIss):                 ErrTokenIssuer,
+		t.sign(t.secret, "hs", future):                   ErrTokenNotYetValid,
+		t.sign(t.secret, "hs", fraction):                 ErrTokenExpired,
+		t.sign(t.secret, "hs", stringExp):                ErrInvalidToken,
+		t.sign(t.secret, "hs", stringNbf):                ErrInvalidToken,
+		t.sign(t.secret, "missing", t.claims(time.Hour)): ErrTokenKeyNotFound,
+		t.sign(otherKey, "es", t.claims(time.Hour)):      ErrTokenSignature,
+		// hmac signed with the rsa public key must not be accepted
+		t.sign(t.rsaKey.PublicKey.N.Bytes(), "rs", t.claims(time.Hour)): ErrTokenAlgorithm,
+	}
+	for token, expected := range tests {
+		_, err := a.Verify(token)
+		t.Equal(expected, err, token)
+	}
+
+	valid := t.claims(0)
+	valid["exp"] = float64(time.Now().Add(time.Hour).Unix()) + 0.5
+	claims, err := a.Verify(t.sign(t.secret, "hs", valid))
+	t.NoError(err)
+	t.Equal(500*time.Millisecond, time.Duration(claims.ExpiresAt().Nanosecond()))
+}
+
+func (t *JWTTestSuite) TestKeysFile() {
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwks := `{"keys":[
+		{"kty":"RSA","kid":"rs","n":"` + b64(t.rsaKey.N.Bytes()) + `","e":"` + b64(big.NewInt(int64(t.rsaKey.E)).Bytes()) + `"},
+		{"kty":"EC","kid":"es","crv":"P-256","x":"` + b64(t.ecKey.X.Bytes()) + `","y":"` + b64(t.ecKey.Y.Bytes()) + `"},
+		{"kty":"oct","kid":"hs","k":"` + b64(t.secret) + `"}
+	]}`
+	dir, err := ioutil.TempDir("", "jwks")
+	t.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jwks.json")
+	t.NoError(ioutil.WriteFile(path, []byte(jwks), 0600))
+	a, err := NewJWTAuth(JWTKeysFile(path), JWTRequireExpiry())
+	t.NoError(err)
+	for kid, key := range map[string]interface{}{"hs": t.secret, "rs": t.rsaKey, "es": t.ecKey} {
+		_, err := a.Verify(t.sign(key, kid, t.claims(time.Hour)))
+		t.NoError(err, kid)
+	}
+	_, err = a.Verify(t.sign(t.secret, "hs", Claims{"sub": "user-1"}))
+	t.Equal(ErrInvalidToken, err)
+
+	_, err = NewJWTAuth(JWTKeysFile(filepath.Join(dir, "missing.json")))
+	t.Error(err)
+	_, err = NewJWTAuth()
+	t.Equal(ErrTokenKeyNotFound, err)
+	_, err = NewJWTAuth(JWTKey("", "string key"))
+	t.Equal(ErrUnsupportedJWTKey, err)
+
+	// empty hmac secrets are rejected
+	_, err = NewJWTAuth(JWTKey("", []byte{}))
+	t.Equal(ErrUnsupportedJWTKey, err)
+	_, err = ParseJWKS([]byte(`{"keys":[{"kty":"oct","kid":"hs","k":""}]}`))
+	t.Equal(ErrUnsupportedJWTKey, err)
+}
+
+func (t *JWTTestSuite) TestServerAndClient() {
+	a, err := NewJWTAuth(JWTKey("", t.secret), JWTAudience("api"))
+	t.NoError(err)
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).
+		Get("/me", func(ctx *Ctx) { ctx.OK(ctx.Claims().Subject() + ":" + ctx.Subject()) })
+	s.SetAuthFunc(a.AuthFunc())
+	go s.Listen()
+	defer ln.Close()
+	dial := func(string) (net.Conn, error) { return ln.Dial() }
+
+	issued := 0
+	cl := NewClient(ln.Addr().String()).SetDialFunc(dial).
+		SetAuthFunc(BearerAuth(func() (string, time.Time, error) {
+			issued++
+			exp := time.Now().Add(time.Hour)
+			if issued == 1 {
+				// expires within the refresh margin
+				exp = time.Now().Add(10 * time.Second)
+			}
+			token, err := SignJWT(t.secret, "", Claims{"sub": "user-1", "aud": "api", "exp": exp.Unix()})
+			return token, exp, err
+		}))
+	for i := 0; i < 3; i++ {
+		rs, err := cl.Get("/me")
+		t.NoError(err)
+		t.Equal(StatusOK, rs.StatusCode())
+		t.Equal(`"user-1:user-1"`, string(rs.Body()))
+	}
+	t.Equal(2, issued)
+
+	rs, err := NewClient(ln.Addr().String()).SetDialFunc(dial).Get("/me")
+	t.NoError(err)
+	t.Equal(StatusUnauthorized, rs.StatusCode())
+}
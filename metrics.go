@@ -0,0 +1,276 @@
+package jsonapi
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MetricsContentType is the content type of Prometheus text format
+	MetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+var (
+	// DefaultDurationBuckets are default latency histogram buckets in seconds
+	DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+	// DefaultSizeBuckets are default response size histogram buckets in bytes
+	DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+)
+
+// NewMetrics creates a new metrics collector, metric names
+// are prefixed with namespace if it is not empty
+func NewMetrics(namespace string) *Metrics {
+	if namespace != "" {
+		namespace += "_"
+	}
+	return &Metrics{
+		namespace:       namespace,
+		mu:              new(sync.Mutex),
+		durationBuckets: DefaultDurationBuckets,
+		sizeBuckets:     DefaultSizeBuckets,
+		server:          newMetricSet(),
+		client:          newMetricSet(),
+	}
+}
+
+// Metrics collects request counts, latency and response size
+// histograms and in-flight gauges of Server routes and Client
+// calls and writes them in Prometheus text format.
+// Server metrics are labeled by method, route pattern and status,
+// Client metrics by method, target host and status
+type Metrics struct {
+	namespace       string
+	mu              *sync.Mutex
+	durationBuckets []float64
+	sizeBuckets     []float64
+	server          *metricSet
+	client          *metricSet
+}
+
+// SetDurationBuckets sets latency histogram buckets in seconds,
+// buckets must be set before metrics are recorded
+func (m *Metrics) SetDurationBuckets(buckets ...float64) *Metrics {
+	m.durationBuckets = sortedBuckets(buckets)
+	return m
+}
+
+// SetSizeBuckets sets response size histogram buckets in bytes,
+// buckets must be set before metrics are recorded
+func (m *Metrics) SetSizeBuckets(buckets ...float64) *Metrics {
+	m.sizeBuckets = sortedBuckets(buckets)
+	return m
+}
+
+// WriteTo writes metrics to w in Prometheus text format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	m.mu.Lock()
+	m.server.write(bw, m.namespace+"http_server_", "served requests")
+	m.client.write(bw, m.namespace+"http_client_", "client requests")
+	m.mu.Unlock()
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// track increments the in-flight gauge and returns a func
+// recording the request when it is done
+func (m *Metrics) track(set *metricSet, method string, label string, value string) func(status string, size int) {
+	start := time.Now()
+	gauge := formatLabels("method", method, label, value)
+	m.mu.Lock()
+	set.inFlight[gauge]++
+	m.mu.Unlock()
+	return func(status string, size int) {
+		labels := formatLabels("method", method, label, value, "status", status)
+		m.mu.Lock()
+		set.inFlight[gauge]--
+		s, ok := set.series[labels]
+		if !ok {
+			s = &metricSeries{
+				duration: newHistogram(m.durationBuckets),
+				size:     newHistogram(m.sizeBuckets),
+			}
+			set.series[labels] = s
+		}
+		s.count++
+		s.duration.observe(time.Since(start).Seconds())
+		s.size.observe(float64(size))
+		m.mu.Unlock()
+	}
+}
+
+func (m *Metrics) trackServer(method string, route string) func(c *Ctx) {
+	done := m.track(m.server, method, "route", route)
+	return func(c *Ctx) {
+		done(strconv.Itoa(c.Response.StatusCode()), responseSize(c))
+	}
+}
+
+// SetMetrics sets a metrics collector recording requests served by routes
+func (s *Server) SetMetrics(m *Metrics) *Server {
+	s.metrics = m
+	return s
+}
+
+// ServeMetrics registers a route on path serving metrics
+// in Prometheus text format. A collector is created if
+// none is set with SetMetrics
+func (s *Server) ServeMetrics(p string, opts ...RouteOption) *Server {
+	if s.metrics == nil {
+		s.metrics = NewMetrics("")
+	}
+	m := s.metrics
+	return s.Get(p, func(ctx *Ctx) {
+		ctx.SetHeader("Content-Type", MetricsContentType)
+		m.WriteTo(ctx)
+	}, append([]RouteOption{Summary("Prometheus metrics")}, opts...)...)
+}
+
+// SetMetrics sets a metrics collector recording client requests
+func (c *Client) SetMetrics(m *Metrics) *Client {
+	c.metrics = m
+	return c
+}
+
+// metricSet holds metrics of server or client requests
+type metricSet struct {
+	series   map[string]*metricSeries // by labels
+	inFlight map[string]int64         // by labels without status
+}
+
+type metricSeries struct {
+	count    uint64
+	duration *histogram
+	size     *histogram
+}
+
+func newMetricSet() *metricSet {
+	return &metricSet{
+		series:   map[string]*metricSeries{},
+		inFlight: map[string]int64{},
+	}
+}
+
+func (set *metricSet) write(w *bufio.Writer, prefix string, what string) {
+	keys := make([]string, 0, len(set.series))
+	for k := range set.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	name := prefix + "requests_total"
+	writeHeader(w, name, "counter", "Total number of "+what+".")
+	for _, k := range keys {
+		writeSample(w, name, k, float64(set.series[k].count))
+	}
+	name = prefix + "request_duration_seconds"
+	writeHeader(w, name, "histogram", "Latency of "+what+" in seconds.")
+	for _, k := range keys {
+		set.series[k].duration.write(w, name, k)
+	}
+	name = prefix + "response_size_bytes"
+	writeHeader(w, name, "histogram", "Response body size of "+what+" in bytes.")
+	for _, k := range keys {
+		set.series[k].size.write(w, name, k)
+	}
+
+	keys = keys[:0]
+	for k := range set.inFlight {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	name = prefix + "requests_in_flight"
+	writeHeader(w, name, "gauge", "Number of "+what+" in flight.")
+	for _, k := range keys {
+		writeSample(w, name, k, float64(set.inFlight[k]))
+	}
+}
+
+// histogram is a Prometheus histogram with non cumulative bucket counts
+type histogram struct {
+	buckets []float64
+	counts  []uint64 // the last one is +Inf
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+func (h *histogram) write(w *bufio.Writer, name string, labels string) {
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+	var cumulative uint64
+	for i, b := range h.buckets {
+		cumulative += h.counts[i]
+		writeSample(w, name+"_bucket", labels+sep+`le="`+formatFloat(b)+`"`, float64(cumulative))
+	}
+	writeSample(w, name+"_bucket", labels+sep+`le="+Inf"`, float64(h.count))
+	writeSample(w, name+"_sum", labels, h.sum)
+	writeSample(w, name+"_count", labels, float64(h.count))
+}
+
+func writeHeader(w *bufio.Writer, name string, typ string, help string) {
+	w.WriteString("# HELP " + name + " " + help + "\n")
+	w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+func writeSample(w *bufio.Writer, name string, labels string, v float64) {
+	w.WriteString(name)
+	if labels != "" {
+		w.WriteString("{" + labels + "}")
+	}
+	w.WriteString(" " + formatFloat(v) + "\n")
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels formats name, value pairs as name1="value1",name2="value2"
+func formatLabels(pairs ...string) string {
+	var sb strings.Builder
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(pairs[i] + `="` + labelEscaper.Replace(pairs[i+1]) + `"`)
+	}
+	return sb.String()
+}
+
+func sortedBuckets(buckets []float64) []float64 {
+	res := append([]float64{}, buckets...)
+	sort.Float64s(res)
+	return res
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,85 @@
+package jsonapi
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestMetrics(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
+
+type MetricsTestSuite struct {
+	suite.Suite
+}
+
+func (t *MetricsTestSuite) TestMetrics() {
+	ln := fasthttputil.NewInmemoryListener()
+	m := NewMetrics("api").SetSizeBuckets(10, 1)
+	s := NewServer().SetListener(ln).
+		SetMetrics(m).
+		Get("/animals/:id", func(ctx *Ctx) { ctx.OK(ctx.GetParamString("id")) }).
+		Get("/stream", func(ctx *Ctx) {
+			ctx.SetBodyStreamWriter(func(w *bufio.Writer) { w.WriteString("streamed") })
+		}).
+		ServeMetrics("/metrics")
+	go s.Listen()
+	defer ln.Close()
+	cl := NewClient("animals.local").
+		SetMetrics(m).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() })
+	for _, uri := range []string{"/animals/1", "/animals/22", "/missing"} {
+		_, err := cl.Get(uri)
+		t.NoError(err)
+	}
+	_, err := NewClient("animals.local").
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }).
+		Get("/stream")
+	t.NoError(err)
+	_, err = NewClient("down.local").
+		SetMetrics(m).
+		SetDialFunc(func(string) (net.Conn, error) { return nil, errors.New("down") }).
+		Get("/")
+	t.Error(err)
+
+	rs, err := cl.Get("/metrics")
+	t.NoError(err)
+	t.Equal(MetricsContentType, string(rs.Header.ContentType()))
+	body := string(rs.Body())
+	for _, line := range []string{
+		"# TYPE api_http_server_requests_total counter",
+		`api_http_server_requests_total{method="GET",route="/animals/:id",status="200"} 2`,
+		`api_http_server_request_duration_seconds_count{method="GET",route="/animals/:id",status="200"} 2`,
+		`api_http_server_response_size_bytes_bucket{method="GET",route="/animals/:id",status="200",le="1"} 0`,
+		`api_http_server_response_size_bytes_bucket{method="GET",route="/animals/:id",status="200",le="10"} 2`,
+		`api_http_server_response_size_bytes_sum{method="GET",route="/animals/:id",status="200"} 7`,
+		`api_http_server_requests_in_flight{method="GET",route="/metrics"} 1`,
+		// body streams are not read
+		`api_http_server_response_size_bytes_sum{method="GET",route="/stream",status="200"} 0`,
+		`api_http_client_requests_total{method="GET",host="animals.local",status="200"} 2`,
+		`api_http_client_requests_total{method="GET",host="animals.local",status="404"} 1`,
+		`api_http_client_requests_total{method="GET",host="down.local",status="error"} 1`,
+		`api_http_client_requests_in_flight{method="GET",host="animals.local"} 1`,
+	} {
+		t.Contains(body, line+"\n")
+	}
+	t.NotContains(body, "/missing")
+}
+
+func (t *MetricsTestSuite) TestWriteTo() {
+	m := NewMetrics("")
+	m.track(m.server, MethodGet, "route", `/a"b`)("200", 1)
+	buf := new(bytes.Buffer)
+	n, err := m.WriteTo(buf)
+	t.NoError(err)
+	t.Equal(int64(buf.Len()), n)
+	t.True(strings.HasPrefix(buf.String(), "# HELP http_server_requests_total"))
+	t.Contains(buf.String(), `http_server_requests_total{method="GET",route="/a\"b",status="200"} 1`)
+}
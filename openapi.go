@@ -0,0 +1,361 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mailru/easyjson"
+)
+
+const (
+	// OpenAPIVersion is the version of generated OpenAPI documents
+	OpenAPIVersion = "3.0.3"
+)
+
+// OpenAPIInfo describes the api in a generated OpenAPI document
+type OpenAPIInfo struct {
+	Title       string
+	Version     string
+	Description string
+	Servers     []string // server urls
+
+	// SecuritySchemes are security schemes by name
+	SecuritySchemes map[string]*OpenAPISecurityScheme
+
+	// Security are names of SecuritySchemes required by not
+	// Public routes, scopes of RequireScopes are listed as required
+	Security []string
+}
+
+// OpenAPIDocument is an OpenAPI 3 document
+type OpenAPIDocument struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       OpenAPIDocumentInfo              `json:"info"`
+	Servers    []OpenAPIServer                  `json:"servers,omitempty"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components OpenAPIComponents                `json:"components"`
+}
+
+// OpenAPIDocumentInfo is the info object of an OpenAPI document
+type OpenAPIDocumentInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIServer is a server object of an OpenAPI document
+type OpenAPIServer struct {
+	URL string `json:"url"`
+}
+
+// OpenAPIComponents holds schemas and security schemes
+// of an OpenAPI document
+type OpenAPIComponents struct {
+	Schemas         map[string]*Schema                `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*OpenAPISecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// OpenAPISecurityScheme is an OpenAPI security scheme, for example:
+// {Type: "http", Scheme: "bearer", BearerFormat: "JWT"}
+// or {Type: "apiKey", In: "header", Name: "X-API-Key"}
+type OpenAPISecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+// Operation is an OpenAPI operation object
+type Operation struct {
+	OperationID string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*MediaBody `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is an OpenAPI parameter object
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody is an OpenAPI request body object
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// MediaBody is an OpenAPI response object
+type MediaBody struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType is an OpenAPI media type object
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is an OpenAPI schema object
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// OpenAPI generates an OpenAPI document from registered routes
+func (s *Server) OpenAPI(info OpenAPIInfo) *OpenAPIDocument {
+	doc := &OpenAPIDocument{
+		OpenAPI: OpenAPIVersion,
+		Info: OpenAPIDocumentInfo{
+			Title:       info.Title,
+			Version:     info.Version,
+			Description: info.Description,
+		},
+		Paths: map[string]map[string]*Operation{},
+		Components: OpenAPIComponents{
+			Schemas:         map[string]*Schema{},
+			SecuritySchemes: info.SecuritySchemes,
+		},
+	}
+	for _, u := range info.Servers {
+		doc.Servers = append(doc.Servers, OpenAPIServer{u})
+	}
+	g := &schemaGenerator{schemas: doc.Components.Schemas, names: map[reflect.Type]string{}}
+	errSchema := g.schema(reflect.TypeOf(Error{}))
+	s.mu.Lock()
+	routes := append([]*route{}, s.routes...)
+	s.mu.Unlock()
+	for _, rt := range routes {
+		p, params := openAPIPath(rt.path)
+		op := &Operation{
+			OperationID: operationID(rt.method, rt.path),
+			Summary:     rt.summary,
+			Tags:        rt.tags,
+			Parameters:  params,
+			Responses: map[string]*MediaBody{
+				"default": jsonMediaBody("error", errSchema),
+			},
+		}
+		if !rt.public {
+			for _, name := range info.Security {
+				scopes := append([]string{}, rt.scopes...)
+				op.Security = append(op.Security, map[string][]string{name: scopes})
+			}
+		}
+		if rt.request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content: map[string]*MediaType{
+					"application/json": {g.schema(reflect.TypeOf(rt.request))},
+				},
+			}
+		}
+		if rt.response != nil {
+			op.Responses["200"] = jsonMediaBody("success", g.schema(reflect.TypeOf(rt.response)))
+		} else {
+			op.Responses["200"] = &MediaBody{Description: "success"}
+		}
+		if len(op.Security) > 0 {
+			op.Responses["401"] = jsonMediaBody("unauthorized", errSchema)
+		}
+		if doc.Paths[p] == nil {
+			doc.Paths[p] = map[string]*Operation{}
+		}
+		doc.Paths[p][strings.ToLower(rt.method)] = op
+	}
+	return doc
+}
+
+// ServeOpenAPI registers a route on path serving
+// the OpenAPI document of the server
+func (s *Server) ServeOpenAPI(p string, info OpenAPIInfo, opts ...RouteOption) *Server {
+	return s.Get(p, func(ctx *Ctx) {
+		ctx.OK(s.OpenAPI(info))
+	}, append([]RouteOption{Summary("OpenAPI document")}, opts...)...)
+}
+
+func jsonMediaBody(description string, schema *Schema) *MediaBody {
+	return &MediaBody{
+		Description: description,
+		Content: map[string]*MediaType{
+			"application/json": {schema},
+		},
+	}
+}
+
+// openAPIPath converts router path parameters (:id and *path)
+// into OpenAPI path parameters
+func openAPIPath(p string) (string, []*Parameter) {
+	var params []*Parameter
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if len(part) > 1 && (part[0] == ':' || part[0] == '*') {
+			params = append(params, &Parameter{
+				Name:     part[1:],
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+// operationID returns an id like getAnimalsId for GET /animals/:id
+func operationID(method string, p string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.FieldsFunc(p, func(r rune) bool {
+		return r == '/' || r == ':' || r == '*' || r == '-' || r == '_' || r == '.'
+	}) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+func listModel(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(v)), 0, 0).Interface()
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	rawEasyType    = reflect.TypeOf(easyjson.RawMessage{})
+)
+
+// schemaGenerator generates schemas from go types,
+// named struct types are added to schemas and referenced
+type schemaGenerator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string // schema names of named types
+}
+
+// schemaName returns the schema name of a named type, types with
+// the name of another type are qualified by the package path
+func (g *schemaGenerator) schemaName(t reflect.Type) (string, bool) {
+	if name, ok := g.names[t]; ok {
+		return name, true
+	}
+	name := t.Name()
+	if g.hasSchema(name) {
+		qualified := strings.Replace(t.PkgPath(), "/", ".", -1) + "." + t.Name()
+		name = qualified
+		for i := 2; g.hasSchema(name); i++ {
+			name = qualified + strconv.Itoa(i)
+		}
+	}
+	g.names[t] = name
+	return name, false
+}
+
+func (g *schemaGenerator) hasSchema(name string) bool {
+	_, ok := g.schemas[name]
+	return ok
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType, rawEasyType:
+		return &Schema{}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schema(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name, ok := g.schemaName(t)
+		if !ok {
+			// reserve the name first to support recursive types
+			g.schemas[name] = nil
+			g.schemas[name] = g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	// interfaces and unsupported kinds accept any value
+	return &Schema{}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.fields(t, s)
+	sort.Strings(s.Required)
+	return s
+}
+
+func (g *schemaGenerator) fields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			name, opts = tag[:i], tag[i:]
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.fields(ft, s)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = g.schema(f.Type)
+		if !strings.Contains(opts, ",omitempty") && f.Type.Kind() != reflect.Ptr {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
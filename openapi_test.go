@@ -0,0 +1,180 @@
+package jsonapi
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestOpenAPI(t *testing.T) {
+	suite.Run(t, new(OpenAPITestSuite))
+}
+
+type OpenAPITestSuite struct {
+	suite.Suite
+}
+
+type apiAnimal struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name,omitempty"`
+	Born     time.Time         `json:"born"`
+	Tags     []string          `json:"tags,omitempty"`
+	Attrs    map[string]int    `json:"attrs,omitempty"`
+	Parent   *apiAnimal        `json:"parent"`
+	Internal string            `json:"-"`
+	Any      interface{}       `json:"any,omitempty"`
+	Extra    map[string]string `json:",omitempty"`
+}
+
+func (a *apiAnimal) MarshalJSON() ([]byte, error) { return []byte(`{}`), nil }
+func (a *apiAnimal) UnmarshalJSON([]byte) error   { return nil }
+func (a *apiAnimal) GetID() ID                    { return a.ID }
+func (a *apiAnimal) SetID(id ID)                  { a.ID = id.(string) }
+
+type apiCRUD struct {
+	BaseController
+}
+
+func (c *apiCRUD) Create(*Ctx) *Result  { return c.OK(nil) }
+func (c *apiCRUD) Get(*Ctx) *Result     { return c.OK(nil) }
+func (c *apiCRUD) GetByID(*Ctx) *Result { return c.OK(nil) }
+func (c *apiCRUD) Update(*Ctx) *Result  { return c.OK(nil) }
+func (c *apiCRUD) Delete(*Ctx) *Result  { return c.OK(nil) }
+func (c *apiCRUD) Model() CRUDModel     { return new(apiAnimal) }
+func (c *apiCRUD) RouteOptions() ControllerRouteOptions {
+	return ControllerRouteOptions{
+		MethodDelete: {"/:id": {Summary("delete animal")}},
+	}
+}
+
+type apiController struct {
+	BaseController
+}
+
+func (c *apiController) Methods() ControllerMethods {
+	return ControllerMethods{
+		MethodGet: {"/files/*path": c.file},
+	}
+}
+
+func (c *apiController) RouteOptions() ControllerRouteOptions {
+	return ControllerRouteOptions{
+		MethodGet: {"/files/*path": {Tags("files"), ResponseModel(BytesResult{})}},
+	}
+}
+
+func (c *apiController) file(*Ctx) *Result { return c.OK(nil) }
+
+func (t *OpenAPITestSuite) server() *Server {
+	return NewServer().
+		CRUDController("/animals", new(apiCRUD)).
+		Controller("/static", new(apiController)).
+		Post("/login", func(*Ctx) {}, Summary("login"), RequestModel(plainStruct{}), ResponseModel(map[string]string{}))
+}
+
+func (t *OpenAPITestSuite) TestOpenAPI() {
+	doc := t.server().OpenAPI(OpenAPIInfo{
+		Title:   "animals",
+		Version: "1.0",
+		Servers: []string{"https://api.local"},
+		SecuritySchemes: map[string]*OpenAPISecurityScheme{
+			"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+		},
+		Security: []string{"bearer"},
+	})
+	t.Equal(OpenAPIVersion, doc.OpenAPI)
+	t.Equal("animals", doc.Info.Title)
+	t.Equal([]OpenAPIServer{{"https://api.local"}}, doc.Servers)
+	t.Len(doc.Paths, 4)
+
+	create := doc.Paths["/animals"]["post"]
+	t.Equal("postAnimals", create.OperationID)
+	t.Equal(&Schema{Ref: "#/components/schemas/apiAnimal"}, create.RequestBody.Content["application/json"].Schema)
+	t.Equal(&Schema{Ref: "#/components/schemas/apiAnimal"}, create.Responses["200"].Content["application/json"].Schema)
+	t.Equal(&Schema{Ref: "#/components/schemas/Error"}, create.Responses["default"].Content["application/json"].Schema)
+	t.NotNil(create.Responses["401"])
+	t.Equal([]map[string][]string{{"bearer": {}}}, create.Security)
+
+	list := doc.Paths["/animals"]["get"]
+	t.Equal(&Schema{Type: "array", Items: &Schema{Ref: "#/components/schemas/apiAnimal"}},
+		list.Responses["200"].Content["application/json"].Schema)
+
+	del := doc.Paths["/animals/{id}"]["delete"]
+	t.Equal("deleteAnimalsId", del.OperationID)
+	t.Equal("delete animal", del.Summary)
+	t.Equal([]*Parameter{{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}}, del.Parameters)
+	t.Nil(del.RequestBody)
+	t.Nil(del.Responses["200"].Content)
+
+	file := doc.Paths["/static/files/{path}"]["get"]
+	t.Equal([]string{"files"}, file.Tags)
+	t.Equal("path", file.Parameters[0].Name)
+	t.Equal(&Schema{Type: "string", Format: "byte"}, file.Responses["200"].Content["application/json"].Schema)
+
+	login := doc.Paths["/login"]["post"]
+	t.Equal("login", login.Summary)
+	t.Equal(&Schema{Ref: "#/components/schemas/plainStruct"}, login.RequestBody.Content["application/json"].Schema)
+	t.Equal(&Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+		login.Responses["200"].Content["application/json"].Schema)
+
+	animal := doc.Components.Schemas["apiAnimal"]
+	t.Equal("object", animal.Type)
+	t.Equal([]string{"born", "id"}, animal.Required)
+	t.Equal(&Schema{Type: "string", Format: "date-time"}, animal.Properties["born"])
+	t.Equal(&Schema{Type: "array", Items: &Schema{Type: "string"}}, animal.Properties["tags"])
+	t.Equal(&Schema{Type: "object", AdditionalProperties: &Schema{Type: "integer", Format: "int32"}}, animal.Properties["attrs"])
+	t.Equal(&Schema{Ref: "#/components/schemas/apiAnimal"}, animal.Properties["parent"])
+	t.Equal(&Schema{}, animal.Properties["any"])
+	t.Contains(animal.Properties, "Extra")
+	t.Len(animal.Properties, 8)
+	t.Equal([]string{"error"}, doc.Components.Schemas["Error"].Required)
+	t.Equal("bearer", doc.Components.SecuritySchemes["bearer"].Scheme)
+}
+
+func (t *OpenAPITestSuite) TestSameTypeNames() {
+	doc := NewServer().
+		Post("/symbols", func(*Ctx) {}, RequestModel(elf.Symbol{}), ResponseModel([]macho.Symbol{})).
+		Post("/errors", func(*Ctx) {}, RequestModel(url.Error{}), ResponseModel(&elf.Symbol{})).
+		OpenAPI(OpenAPIInfo{})
+	symbols := doc.Paths["/symbols"]["post"]
+	t.Equal(&Schema{Ref: "#/components/schemas/Symbol"}, symbols.RequestBody.Content["application/json"].Schema)
+	t.Equal(&Schema{Type: "array", Items: &Schema{Ref: "#/components/schemas/debug.macho.Symbol"}},
+		symbols.Responses["200"].Content["application/json"].Schema)
+	errs := doc.Paths["/errors"]["post"]
+	t.Equal(&Schema{Ref: "#/components/schemas/net.url.Error"}, errs.RequestBody.Content["application/json"].Schema)
+	t.Equal(&Schema{Ref: "#/components/schemas/Symbol"}, errs.Responses["200"].Content["application/json"].Schema)
+	t.Equal([]string{"error"}, doc.Components.Schemas["Error"].Required)
+	t.Contains(doc.Components.Schemas["debug.macho.Symbol"].Properties, "Desc")
+	t.Contains(doc.Components.Schemas["net.url.Error"].Properties, "URL")
+	t.Len(doc.Components.Schemas, 4)
+}
+
+func (t *OpenAPITestSuite) TestServeOpenAPI() {
+	ln, s := new(ServerTestSuite).getServer()
+	s.Get("/a1", func(*Ctx) {}).ServeOpenAPI("/openapi.json", OpenAPIInfo{Title: "test", Version: "1"})
+	go s.Listen()
+	defer ln.Close()
+	rq, rs, err := new(ServerTestSuite).request(ln, MethodGet)
+	t.NoError(err)
+	rq.SetRequestURI("http://" + ln.Addr().String() + "/openapi.json")
+	cl := new(ServerTestSuite).getClient(ln)
+	t.NoError(cl.Do(rq, rs))
+	t.Equal(StatusOK, rs.StatusCode())
+	doc := new(OpenAPIDocument)
+	t.NoError(Unmarshal(rs.Body(), doc))
+	t.Equal("test", doc.Info.Title)
+	t.Contains(doc.Paths, "/a1")
+	t.Contains(doc.Paths, "/openapi.json")
+}
+
+func (t *OpenAPITestSuite) TestOpenAPIPath() {
+	p, params := openAPIPath("/a/:b/c/*d")
+	t.Equal("/a/{b}/c/{d}", p)
+	t.Len(params, 2)
+	t.Equal("getAnimalsAnimalId", operationID(MethodGet, "/animals/:animal_id"))
+	t.Equal("get", operationID(MethodGet, "/"))
+}
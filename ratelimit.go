@@ -0,0 +1,358 @@
+package jsonapi
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	RateLimitTokenBucket   = "token_bucket"
+	RateLimitSlidingWindow = "sliding_window"
+)
+
+// RateLimit is a rate limit of an algorithm
+type RateLimit struct {
+	Algorithm string        // RateLimitTokenBucket or RateLimitSlidingWindow
+	Limit     int           // bucket capacity or requests per window
+	Window    time.Duration // time to refill Limit tokens or window length
+}
+
+// TokenBucket returns a token bucket limit of limit requests per
+// period, bursts of up to limit requests are allowed
+func TokenBucket(limit int, per time.Duration) RateLimit {
+	return RateLimit{Algorithm: RateLimitTokenBucket, Limit: limit, Window: per}
+}
+
+// SlidingWindow returns a sliding window limit of limit requests
+// in any window of length window
+func SlidingWindow(limit int, window time.Duration) RateLimit {
+	return RateLimit{Algorithm: RateLimitSlidingWindow, Limit: limit, Window: window}
+}
+
+// RateLimitStatus is the state of a limit after a request
+type RateLimitStatus struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the limit is fully available again
+	RetryAfter time.Duration // until the next request is allowed if not Allowed
+}
+
+// RateLimitStore keeps rate limit state by key, stores of external
+// backends must apply the limit atomically
+type RateLimitStore interface {
+	// Take takes a request of key from limit
+	Take(key string, limit RateLimit, now time.Time) (RateLimitStatus, error)
+}
+
+// NewMemoryRateLimitStore creates an in-memory RateLimitStore
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{states: map[string]*rateLimitState{}, mu: new(sync.Mutex)}
+}
+
+// MemoryRateLimitStore is an in-memory RateLimitStore,
+// idle keys are removed while taking requests
+type MemoryRateLimitStore struct {
+	states map[string]*rateLimitState
+	mu     *sync.Mutex
+	takes  int
+}
+
+type rateLimitState struct {
+	// token bucket
+	tokens float64
+	last   time.Time
+	// sliding window
+	start time.Time
+	count int
+	prev  int
+
+	expires time.Time
+}
+
+// Take implements RateLimitStore
+func (s *MemoryRateLimitStore) Take(key string, limit RateLimit, now time.Time) (RateLimitStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.takes++
+	if s.takes >= 1000 {
+		s.takes = 0
+		for k, st := range s.states {
+			if now.After(st.expires) {
+				delete(s.states, k)
+			}
+		}
+	}
+	st, ok := s.states[key]
+	if !ok {
+		st = &rateLimitState{tokens: float64(limit.Limit), last: now, start: now.Truncate(limit.Window)}
+		s.states[key] = st
+	}
+	st.expires = now.Add(2 * limit.Window)
+	if limit.Algorithm == RateLimitSlidingWindow {
+		return st.slidingWindow(limit, now), nil
+	}
+	return st.tokenBucket(limit, now), nil
+}
+
+func (st *rateLimitState) tokenBucket(limit RateLimit, now time.Time) RateLimitStatus {
+	rate := float64(limit.Limit) / limit.Window.Seconds() // tokens per second
+	if elapsed := now.Sub(st.last).Seconds(); elapsed > 0 {
+		st.tokens = math.Min(float64(limit.Limit), st.tokens+elapsed*rate)
+		st.last = now
+	}
+	res := RateLimitStatus{Limit: limit.Limit}
+	if st.tokens >= 1 {
+		st.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - st.tokens) / rate)
+	}
+	res.Remaining = int(st.tokens)
+	res.Reset = seconds((float64(limit.Limit) - st.tokens) / rate)
+	return res
+}
+
+func (st *rateLimitState) slidingWindow(limit RateLimit, now time.Time) RateLimitStatus {
+	start := now.Truncate(limit.Window)
+	if !start.Equal(st.start) {
+		if start.Sub(st.start) == limit.Window {
+			st.prev = st.count
+		} else {
+			st.prev = 0
+		}
+		st.start, st.count = start, 0
+	}
+	// the previous window is weighted by its overlap with the sliding window
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(limit.Window)
+	estimate := float64(st.prev)*weight + float64(st.count)
+	res := RateLimitStatus{Limit: limit.Limit, Reset: limit.Window - elapsed}
+	if estimate+1 <= float64(limit.Limit) {
+		st.count++
+		estimate++
+		res.Allowed = true
+	} else if st.count < limit.Limit && st.prev > 0 {
+		// wait until the weight of the previous window allows a request
+		need := 1 - (float64(limit.Limit) - estimate)
+		res.RetryAfter = time.Duration(need / float64(st.prev) * float64(limit.Window))
+	} else {
+		res.RetryAfter = limit.Window - elapsed
+	}
+	if st.count > 0 {
+		// requests of this window count until the end of the next one
+		res.Reset = 2*limit.Window - elapsed
+	}
+	res.Remaining = limit.Limit - int(math.Ceil(estimate))
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// RateLimitKeyFunc returns the rate limit key of a request,
+// requests with an empty key are not limited
+type RateLimitKeyFunc func(*Ctx) string
+
+// RateLimitByIP limits requests by remote ip
+func RateLimitByIP(ctx *Ctx) string {
+	return ctx.RemoteIP().String()
+}
+
+// RateLimitByPrincipal limits authenticated requests by principal
+// subject (for example: user or api key subject) and anonymous
+// requests by remote ip
+func RateLimitByPrincipal(ctx *Ctx) string {
+	if p := ctx.Principal(); p != nil && p.Subject != "" {
+		return "principal:" + p.Subject
+	}
+	return "ip:" + RateLimitByIP(ctx)
+}
+
+// RateLimitByRoute limits requests by method and route pattern
+func RateLimitByRoute(ctx *Ctx) string {
+	return string(ctx.Method()) + " " + ctx.Route()
+}
+
+// RateLimitKeys limits requests by keys of all fns, for example:
+// RateLimitKeys(RateLimitByPrincipal, RateLimitByRoute) limits
+// every principal per route
+func RateLimitKeys(fns ...RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(ctx *Ctx) string {
+		key := ""
+		for i, fn := range fns {
+			k := fn(ctx)
+			if k == "" {
+				return ""
+			}
+			if i > 0 {
+				key += "|"
+			}
+			key += k
+		}
+		return key
+	}
+}
+
+// NewRateLimiter creates a rate limiter of limit, requests are
+// limited by RateLimitByIP in a MemoryRateLimitStore by default.
+// NewRateLimiter panics if the limit or window is not positive
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	if limit.Limit <= 0 || limit.Window <= 0 {
+		panic(fmt.Sprintf("jsonapi: invalid rate limit %d per %s", limit.Limit, limit.Window))
+	}
+	return &RateLimiter{
+		limit:   limit,
+		key:     RateLimitByIP,
+		store:   NewMemoryRateLimitStore(),
+		now:     time.Now,
+		blocked: map[string]time.Time{},
+		mu:      new(sync.Mutex),
+	}
+}
+
+// RateLimiter limits requests, it sets RateLimit-Limit, RateLimit-Remaining,
+// RateLimit-Reset and RateLimit-Policy headers and rejects requests over the
+// limit with ErrTooManyRequests and a Retry-After header. Requests are
+// allowed if the store fails
+type RateLimiter struct {
+	limit   RateLimit
+	key     RateLimitKeyFunc
+	store   RateLimitStore
+	now     func() time.Time
+	blocked map[string]time.Time // keys over the limit of rejected authentication by retry time
+	mu      *sync.Mutex
+	blocks  int
+}
+
+// SetKey sets the func returning rate limit keys of requests
+func (l *RateLimiter) SetKey(fn RateLimitKeyFunc) *RateLimiter {
+	l.key = fn
+	return l
+}
+
+// SetStore sets the store of rate limit state, for example:
+// a store of a backend shared by server instances
+func (l *RateLimiter) SetStore(store RateLimitStore) *RateLimiter {
+	l.store = store
+	return l
+}
+
+// Middleware returns a middleware limiting requests of routes,
+// for example: a stricter limit of a single route with UseMiddleware
+func (l *RateLimiter) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Ctx) {
+			if l.allow(ctx) {
+				next(ctx)
+			}
+		}
+	}
+}
+
+// allow takes a request of c or writes the rate limit error
+func (l *RateLimiter) allow(c *Ctx) bool {
+	key := l.key(c)
+	if key == "" {
+		return true
+	}
+	st, ok := l.take(c, key)
+	if !ok || st.Allowed {
+		return true
+	}
+	l.reject(c, st.RetryAfter)
+	return false
+}
+
+// allowAuth writes the rate limit error if requests of c are blocked
+// for rejected authentication, it is checked before authentication
+func (l *RateLimiter) allowAuth(c *Ctx) bool {
+	key := l.key(c)
+	if key == "" {
+		return true
+	}
+	now := l.now()
+	l.mu.Lock()
+	until, ok := l.blocked[key]
+	if ok && !now.Before(until) {
+		delete(l.blocked, key)
+		ok = false
+	}
+	l.mu.Unlock()
+	if !ok {
+		return true
+	}
+	l.reject(c, until.Sub(now))
+	return false
+}
+
+// rejectAuth takes a request of c rejected by authentication, keys
+// over the limit are blocked until they may retry, so credentials
+// can't be guessed at more than the limit
+func (l *RateLimiter) rejectAuth(c *Ctx) {
+	key := l.key(c)
+	if key == "" {
+		return
+	}
+	st, ok := l.take(c, key)
+	if !ok || st.Allowed {
+		return
+	}
+	now := l.now()
+	l.mu.Lock()
+	l.blocked[key] = now.Add(st.RetryAfter)
+	l.blocks++
+	if l.blocks >= 1000 {
+		l.blocks = 0
+		for k, until := range l.blocked {
+			if !now.Before(until) {
+				delete(l.blocked, k)
+			}
+		}
+	}
+	l.mu.Unlock()
+	l.reject(c, st.RetryAfter)
+}
+
+// take takes a request of key and sets the rate limit headers,
+// it returns FALSE if the store fails
+func (l *RateLimiter) take(c *Ctx, key string) (RateLimitStatus, bool) {
+	// limiters sharing a store are kept apart by their limits
+	prefix := l.limit.Algorithm + ":" + strconv.Itoa(l.limit.Limit) + ":" + l.limit.Window.String() + ":"
+	st, err := l.store.Take(prefix+key, l.limit, l.now())
+	if err != nil {
+		return st, false
+	}
+	c.SetHeader("RateLimit-Limit", strconv.Itoa(st.Limit))
+	c.SetHeader("RateLimit-Remaining", strconv.Itoa(st.Remaining))
+	c.SetHeader("RateLimit-Reset", ceilSeconds(st.Reset))
+	c.SetHeader("RateLimit-Policy", strconv.Itoa(l.limit.Limit)+";w="+ceilSeconds(l.limit.Window))
+	return st, true
+}
+
+// reject writes the rate limit error
+func (l *RateLimiter) reject(c *Ctx, retryAfter time.Duration) {
+	c.SetHeader("Retry-After", ceilSeconds(retryAfter))
+	c.Err(ErrTooManyRequests, StatusTooManyRequests)
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// SetRateLimiter limits requests of all routes with l, requests
+// are limited after authentication so they can be limited by principal.
+// Requests rejected by authentication are limited by their anonymous
+// key (for example: remote ip), clients over the limit are rejected
+// before authentication
+func (s *Server) SetRateLimiter(l *RateLimiter) *Server {
+	s.rateLimiter = l
+	return s
+}
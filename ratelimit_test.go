@@ -0,0 +1,157 @@
+package jsonapi
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestRateLimit(t *testing.T) {
+	suite.Run(t, new(RateLimitTestSuite))
+}
+
+type RateLimitTestSuite struct {
+	suite.Suite
+}
+
+func (t *RateLimitTestSuite) TestTokenBucket() {
+	s := NewMemoryRateLimitStore()
+	limit := TokenBucket(2, time.Second)
+	now := time.Now()
+	st, err := s.Take("a", limit, now)
+	t.NoError(err)
+	t.Equal(RateLimitStatus{Allowed: true, Limit: 2, Remaining: 1, Reset: 500 * time.Millisecond}, st)
+	st, _ = s.Take("a", limit, now)
+	t.True(st.Allowed)
+	t.Equal(0, st.Remaining)
+	st, _ = s.Take("a", limit, now)
+	t.False(st.Allowed)
+	t.Equal(500*time.Millisecond, st.RetryAfter)
+
+	// other keys have own buckets
+	st, _ = s.Take("b", limit, now)
+	t.True(st.Allowed)
+
+	st, _ = s.Take("a", limit, now.Add(500*time.Millisecond))
+	t.True(st.Allowed)
+	st, _ = s.Take("a", limit, now.Add(time.Hour))
+	t.True(st.Allowed)
+	t.Equal(1, st.Remaining)
+}
+
+func (t *RateLimitTestSuite) TestSlidingWindow() {
+	s := NewMemoryRateLimitStore()
+	limit := SlidingWindow(4, time.Minute)
+	start := time.Now().Truncate(time.Minute)
+	for i := 0; i < 4; i++ {
+		st, err := s.Take("a", limit, start.Add(30*time.Second))
+		t.NoError(err)
+		t.True(st.Allowed)
+		t.Equal(3-i, st.Remaining)
+	}
+	st, _ := s.Take("a", limit, start.Add(30*time.Second))
+	t.False(st.Allowed)
+	t.Equal(30*time.Second, st.RetryAfter)
+
+	// 4 requests of the previous window weighted by 5/6
+	next := start.Add(time.Minute + 10*time.Second)
+	st, _ = s.Take("a", limit, next)
+	t.False(st.Allowed)
+	t.Equal(5*time.Second, st.RetryAfter.Round(time.Millisecond))
+	st, _ = s.Take("a", limit, next.Add(5*time.Second))
+	t.True(st.Allowed)
+	t.Equal(0, st.Remaining)
+	t.Equal(105*time.Second, st.Reset)
+
+	// windows without requests reset the limit
+	st, _ = s.Take("a", limit, start.Add(10*time.Minute))
+	t.True(st.Allowed)
+	t.Equal(3, st.Remaining)
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(string, RateLimit, time.Time) (RateLimitStatus, error) {
+	return RateLimitStatus{}, errors.New("store is down")
+}
+
+func (t *RateLimitTestSuite) TestServer() {
+	ln := fasthttputil.NewInmemoryListener()
+	strict := NewRateLimiter(TokenBucket(1, time.Minute)).SetKey(RateLimitKeys(RateLimitByPrincipal, RateLimitByRoute))
+	s := NewServer().SetListener(ln).
+		SetAuthenticator(AuthenticatorFunc(func(ctx *Ctx) (*Principal, error) {
+			if key := ctx.GetHeader("X-Key"); key == "wrong" {
+				return nil, ErrUnauthorized
+			} else if key != "" {
+				return &Principal{Subject: key}, nil
+			}
+			return nil, nil
+		})).
+		SetRateLimiter(NewRateLimiter(SlidingWindow(3, time.Hour)).SetKey(RateLimitByPrincipal)).
+		Get("/ping", func(ctx *Ctx) { ctx.OK("pong") }).
+		Post("/login", func(ctx *Ctx) { ctx.OK(ctx.Route()) }, UseMiddleware(strict.Middleware())).
+		Get("/unlimited", func(ctx *Ctx) { ctx.OK("ok") }, UseMiddleware(
+			NewRateLimiter(TokenBucket(1, time.Hour)).SetStore(failingStore{}).Middleware()))
+	go s.Listen()
+	defer ln.Close()
+	cl := NewClient(ln.Addr().String()).SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() })
+	do := func(method string, uri string, key string) *Response {
+		rs, err := cl.Request().SetMethod(method).SetURI(uri).SetHeader("X-Key", key).Do()
+		t.NoError(err)
+		return rs
+	}
+
+	rs := do(MethodPost, "/login", "alice")
+	t.Equal(StatusOK, rs.StatusCode())
+	t.Equal(`"/login"`, string(rs.Body()))
+	t.Equal("1", string(rs.Header.Peek("RateLimit-Limit")))
+	t.Equal("0", string(rs.Header.Peek("RateLimit-Remaining")))
+	t.Equal("60", string(rs.Header.Peek("RateLimit-Reset")))
+	t.Equal("1;w=60", string(rs.Header.Peek("RateLimit-Policy")))
+
+	rs = do(MethodPost, "/login", "alice")
+	t.Equal(StatusTooManyRequests, rs.StatusCode())
+	t.Equal("60", string(rs.Header.Peek("Retry-After")))
+	t.True(errors.Is(rs.Error(), ErrTooManyRequests))
+
+	// the server limit counts both requests of alice
+	rs = do(MethodGet, "/ping", "alice")
+	t.Equal(StatusOK, rs.StatusCode())
+	t.Equal("3", string(rs.Header.Peek("RateLimit-Limit")))
+	t.Equal("0", string(rs.Header.Peek("RateLimit-Remaining")))
+	rs = do(MethodGet, "/ping", "alice")
+	t.Equal(StatusTooManyRequests, rs.StatusCode())
+
+	rs = do(MethodPost, "/login", "bob")
+	t.Equal(StatusOK, rs.StatusCode())
+	rs = do(MethodGet, "/ping", "")
+	t.Equal(StatusOK, rs.StatusCode())
+
+	// failing stores allow requests
+	for i := 0; i < 2; i++ {
+		rs = do(MethodGet, "/unlimited", "carol")
+		t.Equal(StatusOK, rs.StatusCode())
+	}
+
+	// rejected authentication is limited by ip, the ip is blocked
+	// before authentication when it is over the limit
+	for i := 0; i < 2; i++ {
+		rs = do(MethodGet, "/ping", "wrong")
+		t.Equal(StatusUnauthorized, rs.StatusCode())
+	}
+	rs = do(MethodGet, "/ping", "wrong")
+	t.Equal(StatusTooManyRequests, rs.StatusCode())
+	t.NotEmpty(rs.Header.Peek("Retry-After"))
+	rs = do(MethodGet, "/ping", "carol")
+	t.Equal(StatusTooManyRequests, rs.StatusCode())
+}
+
+func (t *RateLimitTestSuite) TestInvalidLimit() {
+	t.Panics(func() { NewRateLimiter(TokenBucket(0, time.Second)) })
+	t.Panics(func() { NewRateLimiter(SlidingWindow(1, 0)) })
+	t.Panics(func() { NewRateLimiter(RateLimit{Algorithm: RateLimitTokenBucket, Limit: 1, Window: -time.Second}) })
+}
@@ -0,0 +1,102 @@
+package jsonapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	// DefaultRequestIDHeader is the default request id header
+	DefaultRequestIDHeader = "X-Request-ID"
+
+	// maxRequestIDLength is the maximum length of an incoming
+	// request id, longer ids are replaced with generated ones
+	maxRequestIDLength = 128
+
+	requestIDKey = "jsonapi.requestID"
+)
+
+type requestIDContextKey struct{}
+
+// requestIDValue is a request id stored in context.Context
+type requestIDValue struct {
+	header string
+	id     string
+}
+
+// SetRequestIDHeader sets the header used to read and
+// echo request ids, DefaultRequestIDHeader by default
+func (s *Server) SetRequestIDHeader(header string) *Server {
+	s.requestIDHeader = header
+	return s
+}
+
+// SetRequestIDFunc sets a func generating request ids for
+// requests without a request id header, by default
+// random 128 bit hex strings are generated
+func (s *Server) SetRequestIDFunc(fn func() string) *Server {
+	s.requestIDFunc = fn
+	return s
+}
+
+// setRequestID reads or generates the request id of c
+// and echoes it in the response header
+func (s *Server) setRequestID(c *Ctx) {
+	id := c.GetHeader(s.requestIDHeader)
+	if id == "" || len(id) > maxRequestIDLength {
+		id = s.requestIDFunc()
+	}
+	c.SetUserValue(requestIDKey, requestIDValue{s.requestIDHeader, id})
+	c.SetHeader(s.requestIDHeader, id)
+}
+
+// RequestID returns the request id
+func (c *Ctx) RequestID() string {
+	v, _ := c.UserValue(requestIDKey).(requestIDValue)
+	return v.id
+}
+
+// Context returns a context.Context carrying the request id and span,
+// a Client called with this context forwards the request id and
+// propagates the span. The context doesn't refer to the pooled request
+// context, so it can be used after the handler returns
+func (c *Ctx) Context() context.Context {
+	ctx := context.Background()
+	if v, ok := c.UserValue(requestIDKey).(requestIDValue); ok {
+		ctx = context.WithValue(ctx, requestIDContextKey{}, v)
+	}
+	if span := c.Span(); span != nil {
+		ctx = ContextWithSpan(ctx, span)
+	}
+	return ctx
+}
+
+// ContextWithRequestID returns a copy of ctx carrying request id
+// sent by a Client in DefaultRequestIDHeader
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestIDValue{DefaultRequestIDHeader, id})
+}
+
+// RequestIDFromContext returns the request id carried by ctx
+func RequestIDFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(requestIDContextKey{}).(requestIDValue)
+	return v.id
+}
+
+// WithContext returns a copy of the client making requests with ctx.
+// The request id carried by ctx is forwarded, see Ctx.Context
+func (c *Client) WithContext(ctx context.Context) *Client {
+	cc := *c
+	cc.ctx = ctx
+	return &cc
+}
+
+// newRequestID generates a random 128 bit hex string
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,88 @@
+package jsonapi
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestRequestID(t *testing.T) {
+	suite.Run(t, new(RequestIDTestSuite))
+}
+
+type RequestIDTestSuite struct {
+	suite.Suite
+}
+
+func (t *RequestIDTestSuite) client(s *Server) (*Client, func()) {
+	ln := fasthttputil.NewInmemoryListener()
+	go s.SetListener(ln).Listen()
+	return NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }), func() { ln.Close() }
+}
+
+func (t *RequestIDTestSuite) TestRequestID() {
+	s := NewServer().
+		Get("/a1", func(ctx *Ctx) { ctx.OK(ctx.RequestID()) }).
+		Get("/err", func(ctx *Ctx) { ctx.ErrNotFound(NewErrorString("not found")) })
+	cl, closeFn := t.client(s)
+	defer closeFn()
+
+	rs, err := cl.Get("/a1")
+	t.NoError(err)
+	id := string(rs.Header.Peek(DefaultRequestIDHeader))
+	t.Len(id, 32)
+	t.Equal(`"`+id+`"`, string(rs.Body()))
+
+	rs, err = cl.Request().SetMethod(MethodGet).SetURI("/err").
+		SetHeader(DefaultRequestIDHeader, "req-1").Do()
+	t.NoError(err)
+	t.Equal("req-1", string(rs.Header.Peek(DefaultRequestIDHeader)))
+	t.Equal("req-1", rs.Error().(*Error).RequestID)
+}
+
+func (t *RequestIDTestSuite) TestRequestIDConfig() {
+	s := NewServer().
+		SetRequestIDHeader("X-Correlation-ID").
+		SetRequestIDFunc(func() string { return "generated" }).
+		Get("/a1", func(ctx *Ctx) { ctx.OK(ctx.RequestID()) })
+	cl, closeFn := t.client(s)
+	defer closeFn()
+	rs, err := cl.Get("/a1")
+	t.NoError(err)
+	t.Equal("generated", string(rs.Header.Peek("X-Correlation-ID")))
+	t.Empty(rs.Header.Peek(DefaultRequestIDHeader))
+}
+
+func (t *RequestIDTestSuite) TestForward() {
+	upstream := NewServer().
+		Get("/a1", func(ctx *Ctx) { ctx.OK(ctx.RequestID()) })
+	upCl, closeUp := t.client(upstream)
+	defer closeUp()
+	s := NewServer().
+		Get("/proxy", func(ctx *Ctx) {
+			t.Equal(ctx.RequestID(), RequestIDFromContext(ctx.Context()))
+			// values of the pooled request context are not visible
+			t.Nil(ctx.Context().Value(routeKey))
+			rs, err := upCl.WithContext(ctx.Context()).Get("/a1")
+			if err != nil {
+				ctx.ErrBadGateway(err)
+				return
+			}
+			ctx.SetBody(rs.Body())
+		})
+	cl, closeFn := t.client(s)
+	defer closeFn()
+	rs, err := cl.Request().SetMethod(MethodGet).SetURI("/proxy").
+		SetHeader(DefaultRequestIDHeader, "req-2").Do()
+	t.NoError(err)
+	t.Equal(`"req-2"`, string(rs.Body()))
+
+	rs, err = upCl.WithContext(ContextWithRequestID(context.Background(), "req-3")).Get("/a1")
+	t.NoError(err)
+	t.Equal(`"req-3"`, string(rs.Body()))
+	t.Empty(RequestIDFromContext(context.Background()))
+}
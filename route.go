@@ -0,0 +1,339 @@
+package jsonapi
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Middleware wraps a route handler
+type Middleware func(Handler) Handler
+
+type namedMiddleware struct {
+	name string
+	mw   Middleware
+}
+
+// RouteOption configures a route
+type RouteOption func(*route)
+
+// RouteInfo describes a registered route
+type RouteInfo struct {
+	Method       string   `json:"method"`
+	Path         string   `json:"path"`
+	Handler      string   `json:"handler"`
+	Controller   string   `json:"controller,omitempty"`
+	Middleware   []string `json:"middleware,omitempty"`
+	AuthRequired bool     `json:"authRequired"`
+	Scopes       []string `json:"scopes,omitempty"`
+	Roles        []string `json:"roles,omitempty"`
+}
+
+// route is a registered route
+type route struct {
+	method     string
+	path       string
+	handler    interface{} // Handler or ControllerHandler as registered
+	controller string
+	middleware []namedMiddleware
+	serve      Handler // handler wrapped with middleware
+	public     bool
+	scopes     []string
+	roles      []string
+	policies   []Policy
+	summary    string
+	tags       []string
+	request    interface{} // request body model
+	response   interface{} // response body model
+	batchErr   error       // error of batch operations calling the route
+}
+
+func newRoute(method string, p string, handler interface{}, opts []RouteOption) *route {
+	r := &route{
+		method:  method,
+		path:    p,
+		handler: handler,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+const routeKey = "jsonapi.route"
+
+// Route returns the route pattern of the request, for example: /users/:id
+func (c *Ctx) Route() string {
+	r, _ := c.UserValue(routeKey).(string)
+	return r
+}
+
+// wrap wraps handler with route middleware,
+// the first middleware is the outermost one
+func (r *route) wrap(handler Handler) Handler {
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		handler = r.middleware[i].mw(handler)
+	}
+	return handler
+}
+
+func (r *route) info(s *Server) RouteInfo {
+	ri := RouteInfo{
+		Method:       r.method,
+		Path:         r.path,
+		Handler:      funcName(r.handler),
+		Controller:   r.controller,
+		AuthRequired: !r.public && (s.hasAuth || len(r.scopes) > 0 || len(r.roles) > 0),
+		Scopes:       r.scopes,
+		Roles:        r.roles,
+	}
+	for _, m := range r.middleware {
+		ri.Middleware = append(ri.Middleware, m.name)
+	}
+	return ri
+}
+
+// Routes returns registered routes sorted by path and method
+func (s *Server) Routes() []RouteInfo {
+	s.mu.Lock()
+	res := make([]RouteInfo, 0, len(s.routes))
+	for _, r := range s.routes {
+		res = append(res, r.info(s))
+	}
+	s.mu.Unlock()
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Path != res[j].Path {
+			return res[i].Path < res[j].Path
+		}
+		return res[i].Method < res[j].Method
+	})
+	return res
+}
+
+// ServeRoutes registers a debug route on path
+// returning registered routes as json
+func (s *Server) ServeRoutes(p string, opts ...RouteOption) *Server {
+	return s.Get(p, func(ctx *Ctx) {
+		ctx.OK(s.Routes())
+	}, append([]RouteOption{Summary("registered routes")}, opts...)...)
+}
+
+// PrintRoutes writes routes to w as a table
+func PrintRoutes(w io.Writer, routes []RouteInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tHANDLER\tCONTROLLER\tMIDDLEWARE\tAUTH")
+	for _, r := range routes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%t\n",
+			r.Method, r.Path, r.Handler, r.Controller,
+			strings.Join(r.Middleware, ","), r.AuthRequired)
+	}
+	return tw.Flush()
+}
+
+// funcName returns the name of func f without package path
+func funcName(f interface{}) string {
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+	fn := runtime.FuncForPC(v.Pointer())
+	if fn == nil {
+		return ""
+	}
+	name := fn.Name()
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	// method values are suffixed with -fm
+	return strings.TrimSuffix(name, "-fm")
+}
+
+// owner returns the controller or handler name of the route
+func (r *route) owner() string {
+	if r.controller != "" {
+		return r.controller
+	}
+	return funcName(r.handler)
+}
+
+// RouteConflictError is returned when a route can't be registered
+// because it conflicts with an already registered route
+type RouteConflictError struct {
+	Method        string
+	Path          string
+	Owner         string // controller or handler of the new route
+	ConflictPath  string
+	ConflictOwner string // controller or handler of the registered route
+	Segment       string // conflicting segment of Path
+	Duplicate     bool   // the same method and path are registered
+}
+
+// Error implements error interface
+func (e *RouteConflictError) Error() string {
+	if e.Duplicate {
+		return fmt.Sprintf("route %s %s (%s) is already registered by %s",
+			e.Method, e.Path, e.Owner, e.ConflictOwner)
+	}
+	return fmt.Sprintf("route %s %s (%s) conflicts with %s (%s) at segment %q",
+		e.Method, e.Path, e.Owner, e.ConflictPath, e.ConflictOwner, e.Segment)
+}
+
+func newRouteConflictError(rt *route, ex *route, segment string, duplicate bool) *RouteConflictError {
+	return &RouteConflictError{
+		Method:        rt.method,
+		Path:          rt.path,
+		Owner:         rt.owner(),
+		ConflictPath:  ex.path,
+		ConflictOwner: ex.owner(),
+		Segment:       segment,
+		Duplicate:     duplicate,
+	}
+}
+
+// RoutePathError is returned when a route can't be registered
+// because its path has an invalid syntax
+type RoutePathError struct {
+	Path    string
+	Segment string // invalid segment of Path
+	Reason  string
+}
+
+// Error implements error interface
+func (e *RoutePathError) Error() string {
+	if e.Segment == "" {
+		return fmt.Sprintf("route path %q %s", e.Path, e.Reason)
+	}
+	return fmt.Sprintf("route path %q %s at segment %q", e.Path, e.Reason, e.Segment)
+}
+
+// validatePath checks the route path syntax. Wildcards must start
+// a segment: the router panics on catch-all wildcards within
+// a segment and params within a segment conflict with static routes
+func validatePath(p string) error {
+	if p == "" || p[0] != '/' {
+		return &RoutePathError{Path: p, Reason: "must begin with '/'"}
+	}
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		if !isWildcard(part) {
+			if strings.ContainsAny(part, ":*") {
+				return &RoutePathError{Path: p, Segment: part, Reason: "has a wildcard not at the start of a segment"}
+			}
+			continue
+		}
+		if len(part) == 1 {
+			return &RoutePathError{Path: p, Segment: part, Reason: "has an unnamed wildcard"}
+		}
+		if strings.ContainsAny(part[1:], ":*") {
+			return &RoutePathError{Path: p, Segment: part, Reason: "has more than one wildcard in a segment"}
+		}
+		if part[0] == '*' && i != len(parts)-1 {
+			return &RoutePathError{Path: p, Segment: part, Reason: "has a catch-all wildcard not at the end"}
+		}
+	}
+	return nil
+}
+
+// conflictingSegment returns the first segment of path a conflicting
+// with path b in the router tree and TRUE, the segment may be empty
+// (a trailing slash). Wildcards can't share a position with other
+// segments except with a param of the same name, a param may follow
+// a trailing slash
+func conflictingSegment(a string, b string) (string, bool) {
+	pa, pb := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		if pa[i] == pb[i] {
+			continue
+		}
+		if (pa[i] == "" && pb[i][0] == ':') || (pb[i] == "" && pa[i][0] == ':') {
+			return "", false
+		}
+		if isWildcard(pa[i]) || isWildcard(pb[i]) {
+			return pa[i], true
+		}
+		return "", false
+	}
+	return "", false
+}
+
+func isWildcard(part string) bool {
+	return part != "" && (part[0] == ':' || part[0] == '*')
+}
+
+// Summary sets a short route description used in documentation
+func Summary(summary string) RouteOption {
+	return func(r *route) {
+		r.summary = summary
+	}
+}
+
+// Tags sets route tags used to group routes in documentation
+func Tags(tags ...string) RouteOption {
+	return func(r *route) {
+		r.tags = append(r.tags, tags...)
+	}
+}
+
+// RequestModel sets the request body model of the route.
+// v is a value of the model type, for example: new(Animal)
+func RequestModel(v interface{}) RouteOption {
+	return func(r *route) {
+		r.request = v
+	}
+}
+
+// ResponseModel sets the response body model of the route.
+// v is a value of the model type, for example: []*Animal{}
+func ResponseModel(v interface{}) RouteOption {
+	return func(r *route) {
+		r.response = v
+	}
+}
+
+// UseMiddleware adds middleware to the route,
+// it is executed after the server middleware
+func UseMiddleware(mw ...Middleware) RouteOption {
+	return func(r *route) {
+		for _, m := range mw {
+			r.middleware = append(r.middleware, namedMiddleware{funcName(m), m})
+		}
+	}
+}
+
+func inController(ctrl interface{}) RouteOption {
+	return func(r *route) {
+		r.controller = reflect.TypeOf(ctrl).String()
+	}
+}
+
+// ControllerRouteOptions is a map linking http method
+// and controller path to route options
+type ControllerRouteOptions map[string]map[string][]RouteOption
+
+// RouteOptionsController is an optional interface of Controller
+// and CRUDController declaring options of controller routes.
+// Paths are relative to the controller base path, CRUDController
+// uses "/" for Create, Get and "/:id" for GetByID, Update, Delete
+type RouteOptionsController interface {
+	RouteOptions() ControllerRouteOptions
+}
+
+// CRUDModelController is an optional interface of CRUDController
+// returning the model used to document crud routes
+type CRUDModelController interface {
+	Model() CRUDModel
+}
+
+func controllerRouteOptions(ctrl interface{}, method string, p string) []RouteOption {
+	if c, ok := ctrl.(RouteOptionsController); ok {
+		return c.RouteOptions()[method][p]
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package jsonapi
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestRoute(t *testing.T) {
+	suite.Run(t, new(RouteTestSuite))
+}
+
+type RouteTestSuite struct {
+	suite.Suite
+}
+
+func traceMiddleware(name string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Ctx) {
+			ctx.Response.Header.Add("X-Trace", name)
+			next(ctx)
+		}
+	}
+}
+
+func routeHandler(ctx *Ctx) {
+	ctx.OK(nil)
+}
+
+func (t *RouteTestSuite) TestRoutes() {
+	s := NewServer().
+		Get("/b", routeHandler).
+		Use(traceMiddleware("global")).
+		Post("/a", routeHandler, UseMiddleware(traceMiddleware("route"))).
+		Controller("/static", new(apiController))
+	routes := s.Routes()
+	t.Len(routes, 3)
+	t.Equal(MethodPost, routes[0].Method)
+	t.Equal("/a", routes[0].Path)
+	t.Equal("jsonapi.routeHandler", routes[0].Handler)
+	t.Len(routes[0].Middleware, 2)
+	t.False(routes[0].AuthRequired)
+	t.Equal("/b", routes[1].Path)
+	t.Empty(routes[1].Middleware)
+	t.Empty(routes[1].Controller)
+	t.Equal("/static/files/*path", routes[2].Path)
+	t.Equal("*jsonapi.apiController", routes[2].Controller)
+	t.Equal("jsonapi.(*apiController).file", routes[2].Handler)
+	s.SetAuthFunc(func(*Ctx) bool { return true })
+	t.True(s.Routes()[0].AuthRequired)
+}
+
+func (t *RouteTestSuite) TestMiddlewareOrder() {
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).
+		Use(traceMiddleware("a"), traceMiddleware("b")).
+		Get("/a1", routeHandler, UseMiddleware(traceMiddleware("c")))
+	go s.Listen()
+	defer ln.Close()
+	rs, err := NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }).
+		Get("/a1")
+	t.NoError(err)
+	t.Equal(StatusOK, rs.StatusCode())
+	var trace []string
+	rs.Header.VisitAll(func(k, v []byte) {
+		if string(k) == "X-Trace" {
+			trace = append(trace, string(v))
+		}
+	})
+	t.Equal([]string{"a", "b", "c"}, trace)
+}
+
+func (t *RouteTestSuite) TestServeRoutes() {
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).
+		Get("/a1", routeHandler).
+		ServeRoutes("/debug/routes")
+	go s.Listen()
+	defer ln.Close()
+	rs, err := NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }).
+		Get("/debug/routes")
+	t.NoError(err)
+	t.Equal(StatusOK, rs.StatusCode())
+	var routes []RouteInfo
+	t.NoError(rs.ReadJSON(&routes))
+	t.Equal(s.Routes(), routes)
+	buf := new(bytes.Buffer)
+	t.NoError(PrintRoutes(buf, routes))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	t.Len(lines, 3)
+	t.True(strings.HasPrefix(lines[0], "METHOD"))
+	t.Contains(lines[1], "/a1")
+	t.Contains(lines[2], "/debug/routes")
+}
+
+type conflictController struct {
+	BaseController
+}
+
+func (c *conflictController) Methods() ControllerMethods {
+	return ControllerMethods{
+		MethodGet: {
+			"/:name":      c.get,
+			"/files/*all": c.get,
+		},
+	}
+}
+
+func (c *conflictController) get(*Ctx) *Result { return c.OK(nil) }
+
+func (t *RouteTestSuite) TestAddRouteConflict() {
+	s := NewServer()
+	t.NoError(s.AddRoute(MethodGet, "/a/:id", routeHandler))
+	t.NoError(s.AddRoute(MethodGet, "/a/:id/b", routeHandler))
+	t.NoError(s.AddRoute(MethodPost, "/a/new", routeHandler))
+	err := s.AddRoute(MethodGet, "/a/new", routeHandler)
+	t.IsType(&RouteConflictError{}, err)
+	ce := err.(*RouteConflictError)
+	t.Equal("/a/:id", ce.ConflictPath)
+	t.Equal("new", ce.Segment)
+	t.False(ce.Duplicate)
+	t.Contains(err.Error(), "jsonapi.routeHandler")
+	t.Error(s.AddRoute(MethodGet, "/a/:name", routeHandler))
+	t.Error(s.AddRoute(MethodGet, "/a/*all", routeHandler))
+	t.Error(s.AddRoute(MethodGet, "a", routeHandler))
+	t.Error(s.AddRoute(MethodGet, "/b/*all/c", routeHandler))
+	t.Error(s.AddRoute(MethodGet, "/b/:", routeHandler))
+	t.NoError(s.AddRoute(MethodGet, "/c/", routeHandler))
+	t.NoError(s.AddRoute(MethodGet, "/c/:id", routeHandler))
+	err = s.AddRoute(MethodGet, "/c/*all", routeHandler)
+	t.IsType(&RouteConflictError{}, err)
+	t.Equal("*all", err.(*RouteConflictError).Segment)
+	t.NoError(s.AddRoute(MethodGet, "/d/x", routeHandler))
+	err = s.AddRoute(MethodGet, "/d/*all", routeHandler)
+	t.IsType(&RouteConflictError{}, err)
+	t.False(err.(*RouteConflictError).Duplicate)
+	t.Len(s.Routes(), 6)
+	t.Empty(s.Replaced())
+	t.NoError(s.Err())
+	t.Panics(func() {
+		s.MustRoute(MethodGet, "/a/:name", routeHandler)
+	})
+}
+
+func (t *RouteTestSuite) TestInvalidPath() {
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).
+		Get("/user/:name", func(ctx *Ctx) { ctx.OK(ctx.GetParamString("name")) }).
+		Get("/user_x", routeHandler).
+		Get("/files/*path", func(ctx *Ctx) { ctx.OK(ctx.GetParamString("path")) })
+	t.NoError(s.Err())
+	tests := []struct {
+		path    string
+		segment string
+	}{
+		{"user", ""},
+		{"/user_:name", "user_:name"},
+		{"/files/x*path", "x*path"},
+		{"/a:b/c", "a:b"},
+		{"/x/:id/y*z", "y*z"},
+		{"/b/:", ":"},
+		{"/b/:id:name", ":id:name"},
+		{"/b/*all/c", "*all"},
+	}
+	for _, tt := range tests {
+		// the router panics or conflicts with valid routes otherwise
+		var err error
+		t.NotPanics(func() { err = s.AddRoute(MethodGet, tt.path, routeHandler) }, tt.path)
+		t.IsType(&RoutePathError{}, err, tt.path)
+		if pe, ok := err.(*RoutePathError); ok {
+			t.Equal(tt.path, pe.Path)
+			t.Equal(tt.segment, pe.Segment, tt.path)
+		}
+	}
+	t.Len(s.Routes(), 3)
+	go s.Listen()
+	defer ln.Close()
+	cl := NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() })
+	for uri, body := range map[string]string{"/user/bob": `"bob"`, "/files/a/b": `"/a/b"`} {
+		rs, err := cl.Get(uri)
+		t.NoError(err)
+		t.Equal(StatusOK, rs.StatusCode(), uri)
+		t.Equal(body, string(rs.Body()), uri)
+	}
+}
+
+func (t *RouteTestSuite) TestDuplicateRoute() {
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).
+		Get("/a1", routeHandler).
+		Get("/a1", func(ctx *Ctx) { ctx.OK("second") })
+	t.NoError(s.Err())
+	t.Len(s.Routes(), 1)
+	go s.Listen()
+	defer ln.Close()
+	rs, err := NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }).
+		Get("/a1")
+	t.NoError(err)
+	t.Equal(`"second"`, string(rs.Body()))
+	t.Len(s.Replaced(), 1)
+	t.True(s.Replaced()[0].Duplicate)
+	t.Equal("/a1", s.Replaced()[0].ConflictPath)
+
+	s = NewServer().SetStrict(true)
+	t.NoError(s.AddRoute(MethodGet, "/a1", routeHandler))
+	err = s.AddRoute(MethodGet, "/a1", routeHandler)
+	t.IsType(&RouteConflictError{}, err)
+	t.True(err.(*RouteConflictError).Duplicate)
+}
+
+func (t *RouteTestSuite) TestControllerConflict() {
+	s := NewServer("127.0.0.1:").
+		Controller("/", new(apiController)).
+		Controller("/", new(conflictController))
+	err := s.Err()
+	t.Error(err)
+	t.IsType(RouteErrors{}, err)
+	t.Len(err.(RouteErrors), 2)
+	t.Contains(err.Error(), "*jsonapi.conflictController")
+	t.Contains(err.Error(), "*jsonapi.apiController")
+	t.Equal(err, s.Listen())
+}
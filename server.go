@@ -1,12 +1,17 @@
 package jsonapi
 
 import (
+	"crypto/tls"
 	"net"
 	"os"
 	"path"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/buaazp/fasthttprouter"
+	"github.com/fasthttp/websocket"
 	"github.com/valyala/fasthttp"
 )
 
@@ -23,16 +28,19 @@ const (
 	MethodOptions = "OPTIONS"
 	MethodTrace   = "TRACE"
 
-	StatusOK                  = 200
-	StatusBadRequest          = 400
-	StatusUnauthorized        = 401
-	StatusForbidden           = 403
-	StatusNotFound            = 404
-	StatusMethodNotAllowed    = 405
-	StatusInternalServerError = 500
-	StatusBadGateway          = 502
-	StatusServiceUnavailable  = 503
-	StatusGatewayTimeout      = 504
+	StatusOK                    = 200
+	StatusBadRequest            = 400
+	StatusUnauthorized          = 401
+	StatusForbidden             = 403
+	StatusNotFound              = 404
+	StatusMethodNotAllowed      = 405
+	StatusRequestEntityTooLarge = 413
+	StatusFailedDependency      = 424
+	StatusTooManyRequests       = 429
+	StatusInternalServerError   = 500
+	StatusBadGateway            = 502
+	StatusServiceUnavailable    = 503
+	StatusGatewayTimeout        = 504
 )
 
 // Handler defines the handler func
@@ -44,9 +52,14 @@ type ControllerHandler func(*Ctx) *Result
 // NewServer creates a new jsonapi Server
 func NewServer(addr ...string) *Server {
 	s := &Server{
-		router:   fasthttprouter.New(),
-		authFunc: func(*Ctx) bool { return true },
-		mu:       new(sync.Mutex),
+		router:       fasthttprouter.New(),
+		authFunc:     func(*Ctx) bool { return true },
+		mu:           new(sync.Mutex),
+		sseHeartbeat: DefaultSSEHeartbeat,
+		wsUpgrader:   newWebSocketUpgrader(),
+
+		requestIDHeader: DefaultRequestIDHeader,
+		requestIDFunc:   newRequestID,
 	}
 	s.mu.Lock()
 	if len(addr) == 1 {
@@ -60,15 +73,49 @@ func NewServer(addr ...string) *Server {
 
 // Server is an http server wrapper
 type Server struct {
-	addr     string
-	authFunc ServerAuthFunc
-	ln       net.Listener
-	router   *fasthttprouter.Router
-	mu       *sync.Mutex
+	addr          string
+	authFunc      ServerAuthFunc
+	authenticator Authenticator
+	ln            net.Listener
+	router        *fasthttprouter.Router
+	mu            *sync.Mutex
+	sseHeartbeat  time.Duration
+	wsUpgrader    *websocket.FastHTTPUpgrader
+	routes        []*route
+	middleware    []namedMiddleware
+	hasAuth       bool
+	strict        bool
+	errs          RouteErrors
+	replaced      []*RouteConflictError
+	errorMappers  []ErrorMapper
+
+	requestIDHeader string
+	requestIDFunc   func() string
+	accessLog       *accessLog
+	metrics         *Metrics
+	tracer          *Tracer
+	policy          Policy
+	rateLimiter     *RateLimiter
+	tlsConfig       *tls.Config
+}
+
+// RouteErrors are errors of route registrations
+type RouteErrors []error
+
+// Error implements error interface
+func (e RouteErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
 }
 
 // Listen starts http server and listens on defined addr
 func (s *Server) Listen() error {
+	if err := s.Err(); err != nil {
+		return err
+	}
 	if err := s.newListener(); err != nil {
 		return err
 	}
@@ -76,8 +123,19 @@ func (s *Server) Listen() error {
 }
 
 // ListenTLS starts http server and listens on defined addr with TLS
-// Reads TLS certificate from certFile and key from keyFile
+// Reads TLS certificate from certFile and key from keyFile,
+// the TLS config of SetTLSConfig is used if set
 func (s *Server) ListenTLS(certFile string, keyFile string) error {
+	if err := s.Err(); err != nil {
+		return err
+	}
+	if s.tlsConfig != nil {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		return s.serveTLS(s.tlsConfig, cert)
+	}
 	if err := s.newListener(); err != nil {
 		return err
 	}
@@ -85,8 +143,19 @@ func (s *Server) ListenTLS(certFile string, keyFile string) error {
 }
 
 // ListenTLSEmbed starts http server and listens on defined addr with TLS
-// Accepts TLS certificate in cert and key in key
+// Accepts TLS certificate in cert and key in key,
+// the TLS config of SetTLSConfig is used if set
 func (s *Server) ListenTLSEmbed(cert []byte, key []byte) error {
+	if err := s.Err(); err != nil {
+		return err
+	}
+	if s.tlsConfig != nil {
+		c, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return err
+		}
+		return s.serveTLS(s.tlsConfig, c)
+	}
 	if err := s.newListener(); err != nil {
 		return err
 	}
@@ -96,6 +165,9 @@ func (s *Server) ListenTLSEmbed(cert []byte, key []byte) error {
 // ListenUNIX starts http server and listens on UNIX socket
 // Accepts mode as file mode
 func (s *Server) ListenUNIX(mode os.FileMode) error {
+	if err := s.Err(); err != nil {
+		return err
+	}
 	return fasthttp.ListenAndServeUNIX(s.addr, mode, s.router.Handler)
 }
 
@@ -119,110 +191,283 @@ func (s *Server) GetAddr() string {
 }
 
 // SetAuthFunc sets authentication func that will be triggered
-// on every request to the server
+// on every request to the server, see also SetAuthenticator
 func (s *Server) SetAuthFunc(authFunc ServerAuthFunc) {
 	s.authFunc = authFunc
+	s.SetAuthenticator(AuthFuncAuthenticator(authFunc))
+}
+
+// Use adds middleware applied to routes registered after the call.
+// Middleware is executed in order of addition, after authentication
+func (s *Server) Use(mw ...Middleware) *Server {
+	s.mu.Lock()
+	for _, m := range mw {
+		s.middleware = append(s.middleware, namedMiddleware{funcName(m), m})
+	}
+	s.mu.Unlock()
+	return s
+}
+
+// SetStrict enables or disables strict mode. In strict mode registering
+// the same method and path twice is an error, otherwise the last
+// registration replaces the previous one and the conflict is
+// returned by Replaced
+func (s *Server) SetStrict(strict bool) *Server {
+	s.mu.Lock()
+	s.strict = strict
+	s.mu.Unlock()
+	return s
+}
+
+// Err returns RouteErrors of failed route registrations or nil.
+// Listen methods return this error before serving
+func (s *Server) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.errs) == 0 {
+		return nil
+	}
+	return append(RouteErrors{}, s.errs...)
+}
+
+// Replaced returns conflicts of duplicate routes replaced
+// by later registrations when strict mode is disabled
+func (s *Server) Replaced() []*RouteConflictError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*RouteConflictError{}, s.replaced...)
 }
 
-// Route adds a new route handler to router
-func (s *Server) Route(method string, p string, handler Handler) *Server {
-	s.router.Handle(method, p, func(ctx *fasthttp.RequestCtx) {
+// Route adds a new route handler to router.
+// Registration errors are returned by Err
+func (s *Server) Route(method string, p string, handler Handler, opts ...RouteOption) *Server {
+	s.addErr(s.AddRoute(method, p, handler, opts...))
+	return s
+}
+
+// AddRoute adds a new route handler to router or returns an error,
+// *RouteConflictError if the route conflicts with a registered route
+func (s *Server) AddRoute(method string, p string, handler Handler, opts ...RouteOption) error {
+	return s.handle(newRoute(method, p, handler, opts), handler)
+}
+
+// MustRoute is like Route but panics if the route can't be registered
+func (s *Server) MustRoute(method string, p string, handler Handler, opts ...RouteOption) *Server {
+	if err := s.AddRoute(method, p, handler, opts...); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (s *Server) addErr(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.errs = append(s.errs, err)
+	s.mu.Unlock()
+}
+
+// handle validates rt and registers it in the route table and router
+func (s *Server) handle(rt *route, handler Handler) error {
+	if err := validatePath(rt.path); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rt.middleware = append(append([]namedMiddleware{}, s.middleware...), rt.middleware...)
+	rt.serve = rt.wrap(handler)
+	for _, ex := range s.routes {
+		if ex.method != rt.method {
+			continue
+		}
+		if ex.path == rt.path {
+			err := newRouteConflictError(rt, ex, "", true)
+			if s.strict {
+				return err
+			}
+			s.replaced = append(s.replaced, err)
+			// the router keeps ex, replace it with the new route
+			*ex = *rt
+			return nil
+		}
+		if seg, ok := conflictingSegment(rt.path, ex.path); ok {
+			return newRouteConflictError(rt, ex, seg, false)
+		}
+	}
+	s.routes = append(s.routes, rt)
+	s.router.Handle(rt.method, rt.path, func(ctx *fasthttp.RequestCtx) {
 		c := &Ctx{ctx}
+		if l := s.accessLog; l != nil {
+			defer l.log(c, rt, time.Now())
+		}
+		if m := s.metrics; m != nil {
+			defer m.trackServer(rt.method, rt.path)(c)
+		}
 		c.SetHeader("Content-Type", "application/json")
 		c.SetHeader("Server", "jsonapi @ fasthttp")
+		s.setRequestID(c)
+		c.SetUserValue(routeKey, rt.path)
+		c.SetUserValue(serverKey, s)
+		if rejectBatch(c, rt) {
+			return
+		}
+		if t := s.tracer; t != nil {
+			span := s.startSpan(c, rt)
+			defer func() {
+				t.end(span, c.Response.StatusCode(), c.errCause())
+			}()
+		}
 		// check auth
-		if !s.authFunc(c) {
-			// return unauthorized error
-			c.ErrUnauthorized(ErrUnauthorized)
+		if !rt.public {
+			l := s.rateLimiter
+			if l != nil && !l.allowAuth(c) {
+				return
+			}
+			if !s.authenticate(c) {
+				// failures of auth backends aren't rejected credentials
+				if l != nil && c.Response.StatusCode() < StatusInternalServerError {
+					l.rejectAuth(c)
+				}
+				return
+			}
+			if !s.authorize(c, rt) {
+				return
+			}
+		}
+		if l := s.rateLimiter; l != nil && !l.allow(c) {
 			return
 		}
 		// execute handler
-		handler(c)
+		rt.serve(c)
 	})
-	return s
+	return nil
 }
 
 // Get is a shortcut to Route("GET"...)
-func (s *Server) Get(p string, handler Handler) *Server {
-	return s.Route(MethodGet, p, handler)
+func (s *Server) Get(p string, handler Handler, opts ...RouteOption) *Server {
+	return s.Route(MethodGet, p, handler, opts...)
 }
 
 // Head is a shortcut to Route("HEAD"...)
-func (s *Server) Head(p string, handler Handler) *Server {
-	return s.Route(MethodHead, p, handler)
+func (s *Server) Head(p string, handler Handler, opts ...RouteOption) *Server {
+	return s.Route(MethodHead, p, handler, opts...)
 }
 
 // Post is a shortcut to Route("POST"...)
-func (s *Server) Post(p string, handler Handler) *Server {
-	return s.Route(MethodPost, p, handler)
+func (s *Server) Post(p string, handler Handler, opts ...RouteOption) *Server {
+	return s.Route(MethodPost, p, handler, opts...)
 }
 
 // Put is a shortcut to Route("PUT"...)
-func (s *Server) Put(p string, handler Handler) *Server {
-	return s.Route(MethodPut, p, handler)
+func (s *Server) Put(p string, handler Handler, opts ...RouteOption) *Server {
+	return s.Route(MethodPut, p, handler, opts...)
 }
 
 // Patch is a shortcut to Route("PATCH"...)
-func (s *Server) Patch(p string, handler Handler) *Server {
-	return s.Route(MethodPatch, p, handler)
+func (s *Server) Patch(p string, handler Handler, opts ...RouteOption) *Server {
+	return s.Route(MethodPatch, p, handler, opts...)
 }
 
 // Delete is a shortcut to Route("DELETE"...)
-func (s *Server) Delete(p string, handler Handler) *Server {
-	return s.Route(MethodDelete, p, handler)
+func (s *Server) Delete(p string, handler Handler, opts ...RouteOption) *Server {
+	return s.Route(MethodDelete, p, handler, opts...)
 }
 
 // Connect is a shortcut to Route("CONNECT"...)
-func (s *Server) Connect(p string, handler Handler) *Server {
-	return s.Route(MethodConnect, p, handler)
+func (s *Server) Connect(p string, handler Handler, opts ...RouteOption) *Server {
+	return s.Route(MethodConnect, p, handler, opts...)
 }
 
 // Options is a shortcut to Route("OPTIONS"...)
-func (s *Server) Options(p string, handler Handler) *Server {
-	return s.Route(MethodOptions, p, handler)
+func (s *Server) Options(p string, handler Handler, opts ...RouteOption) *Server {
+	return s.Route(MethodOptions, p, handler, opts...)
 }
 
 // Trace is a shortcut to Route("TRACE"...)
-func (s *Server) Trace(p string, handler Handler) *Server {
-	return s.Route(MethodTrace, p, handler)
+func (s *Server) Trace(p string, handler Handler, opts ...RouteOption) *Server {
+	return s.Route(MethodTrace, p, handler, opts...)
 }
 
 // ControllerMethod registers a controller method handler by http method and path
 // This method can be called directly without a controller.
-func (s *Server) ControllerMethod(method string, p string, handler ControllerHandler) *Server {
-	s.Route(method, p, func(ctx *Ctx) {
+// Registration errors are returned by Err
+func (s *Server) ControllerMethod(method string, p string, handler ControllerHandler, opts ...RouteOption) *Server {
+	s.addErr(s.handle(newRoute(method, p, handler, opts), func(ctx *Ctx) {
 		res := handler(ctx)
+		if res.Err == nil && res.Cause != nil {
+			res.Err = s.requestError(ctx, res.Cause)
+		}
 		if res.Err != nil {
 			ctx.Err(res.Err, res.Err.Code)
 			return
 		}
 		ctx.OK(res.Data)
-	})
+	}))
 	return s
 }
 
-// Controller registers a controller
+// Controller registers a controller, routes are registered
+// in order of method and path. Registration errors are returned by Err
 func (s *Server) Controller(basePath string, ctrl Controller) *Server {
-	for method, paths := range ctrl.Methods() {
-		for p, handler := range paths {
-			s.ControllerMethod(method, path.Join(basePath, p), handler)
+	methods := ctrl.Methods()
+	var routes [][2]string
+	for method, paths := range methods {
+		for p := range paths {
+			routes = append(routes, [2]string{method, p})
 		}
 	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i][0] != routes[j][0] {
+			return routes[i][0] < routes[j][0]
+		}
+		return routes[i][1] < routes[j][1]
+	})
+	for _, r := range routes {
+		method, p := r[0], r[1]
+		opts := append(controllerRouteOptions(ctrl, method, p), inController(ctrl))
+		s.ControllerMethod(method, path.Join(basePath, p), methods[method][p], opts...)
+	}
 	return s
 }
 
 // CRUDController assigns a crud controller to a path
 func (s *Server) CRUDController(path string, ctrl CRUDController) *Server {
+	var model interface{}
+	if m, ok := ctrl.(CRUDModelController); ok {
+		model = m.Model()
+	}
+	var access CRUDAccess
+	if a, ok := ctrl.(CRUDAccessController); ok {
+		access = a.Access()
+	}
+	opts := func(method string, p string, docs ...RouteOption) []RouteOption {
+		if model == nil {
+			docs = nil
+		}
+		if method == MethodGet {
+			docs = append(docs, access.Read...)
+		} else {
+			docs = append(docs, access.Write...)
+		}
+		docs = append(docs, controllerRouteOptions(ctrl, method, p)...)
+		return append(docs, inController(ctrl))
+	}
 	// handle POST/Create
-	s.ControllerMethod(MethodPost, path, ctrl.Create)
+	s.ControllerMethod(MethodPost, path, ctrl.Create,
+		opts(MethodPost, "/", RequestModel(model), ResponseModel(model))...)
 	// handler GET/Get
-	s.ControllerMethod(MethodGet, path, ctrl.Get)
+	s.ControllerMethod(MethodGet, path, ctrl.Get,
+		opts(MethodGet, "/", ResponseModel(listModel(model)))...)
 	// Handle GET/GetByID
-	s.ControllerMethod(MethodGet, getCrudPath(path), ctrl.GetByID)
+	s.ControllerMethod(MethodGet, getCrudPath(path), ctrl.GetByID,
+		opts(MethodGet, "/:id", ResponseModel(model))...)
 	// Handle PUT/Update
-	s.ControllerMethod(MethodPut, getCrudPath(path), ctrl.Update)
+	s.ControllerMethod(MethodPut, getCrudPath(path), ctrl.Update,
+		opts(MethodPut, "/:id", RequestModel(model), ResponseModel(model))...)
 	// Handle DELETE/Delete
-	s.ControllerMethod(MethodDelete, getCrudPath(path), ctrl.Delete)
+	s.ControllerMethod(MethodDelete, getCrudPath(path), ctrl.Delete,
+		opts(MethodDelete, "/:id")...)
 	return s
 }
 
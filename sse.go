@@ -0,0 +1,315 @@
+package jsonapi
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// DefaultSSEHeartbeat is the default interval between
+	// heartbeat comments sent to keep the connection alive
+	DefaultSSEHeartbeat = 15 * time.Second
+
+	// DefaultSSERetry is the default client reconnection delay
+	DefaultSSERetry = 3 * time.Second
+
+	// MaxSSELineSize is the maximum size of an event stream line
+	// read by the client, longer lines fail with bufio.ErrTooLong
+	MaxSSELineSize = 1 << 20
+)
+
+var (
+	// ErrStreamClosed is returned when writing to a closed event stream
+	ErrStreamClosed = errors.New("event stream is closed")
+
+	// ErrStopEvents can be returned from an event callback
+	// to stop reading events without an error
+	ErrStopEvents = errors.New("stop events")
+)
+
+// SSEHandler defines server-sent events handler func.
+// Events are sent to the client until the func returns
+type SSEHandler func(*Ctx, *EventStream) error
+
+// Event is a server-sent event
+type Event struct {
+	ID    string        // event id, sent back by the client in Last-Event-ID
+	Name  string        // event type, "message" if empty
+	Data  []byte        // event data, json encoded
+	Retry time.Duration // client reconnection delay, not sent if zero
+}
+
+// ReadJSON reads json into v from event data
+func (e *Event) ReadJSON(v interface{}) error {
+	return Unmarshal(e.Data, v)
+}
+
+// EventStream is a stream of server-sent events to a single client
+type EventStream struct {
+	lastEventID string
+	w           *bufio.Writer
+	mu          sync.Mutex
+	err         error
+	done        chan struct{}
+}
+
+// LastEventID returns the Last-Event-ID sent by a reconnecting client
+func (s *EventStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Done returns a channel that is closed when the stream is closed
+// (for example: the client has gone away)
+func (s *EventStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Send sends an event with json encoded v as data
+func (s *EventStream) Send(name string, v interface{}) error {
+	return s.SendID("", name, v)
+}
+
+// SendID sends an event with id and json encoded v as data
+func (s *EventStream) SendID(id string, name string, v interface{}) error {
+	b, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.WriteEvent(&Event{ID: id, Name: name, Data: b})
+}
+
+// Retry sets the client reconnection delay
+func (s *EventStream) Retry(d time.Duration) error {
+	return s.WriteEvent(&Event{Retry: d})
+}
+
+// Comment sends a comment line, which is ignored by clients
+func (s *EventStream) Comment(text string) error {
+	return s.write(func(w *bufio.Writer) {
+		for _, line := range strings.Split(text, "\n") {
+			w.WriteString(": ")
+			w.WriteString(line)
+			w.WriteByte('\n')
+		}
+		w.WriteByte('\n')
+	})
+}
+
+// WriteEvent sends e to the client
+func (s *EventStream) WriteEvent(e *Event) error {
+	return s.write(func(w *bufio.Writer) {
+		if e.ID != "" {
+			w.WriteString("id: ")
+			w.WriteString(e.ID)
+			w.WriteByte('\n')
+		}
+		if e.Name != "" {
+			w.WriteString("event: ")
+			w.WriteString(e.Name)
+			w.WriteByte('\n')
+		}
+		if e.Retry > 0 {
+			w.WriteString("retry: ")
+			w.WriteString(strconv.FormatInt(int64(e.Retry/time.Millisecond), 10))
+			w.WriteByte('\n')
+		}
+		if e.Data != nil {
+			for _, line := range bytes.Split(e.Data, []byte("\n")) {
+				w.WriteString("data: ")
+				w.Write(line)
+				w.WriteByte('\n')
+			}
+		}
+		w.WriteByte('\n')
+	})
+}
+
+func (s *EventStream) write(fn func(w *bufio.Writer)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	fn(s.w)
+	if err := s.w.Flush(); err != nil {
+		s.close(err)
+		return err
+	}
+	return nil
+}
+
+// close must be called with mu held
+func (s *EventStream) close(err error) {
+	if s.err == nil {
+		s.err = err
+		close(s.done)
+	}
+}
+
+func (s *EventStream) heartbeat(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	t := time.NewTicker(d)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-t.C:
+			s.write(func(w *bufio.Writer) {
+				w.WriteString(":\n\n")
+			})
+		}
+	}
+}
+
+// SetSSEHeartbeat sets the interval between heartbeat comments
+// sent on event streams. d <= 0 disables heartbeats
+func (s *Server) SetSSEHeartbeat(d time.Duration) *Server {
+	s.sseHeartbeat = d
+	return s
+}
+
+// SSE registers a server-sent events handler on path.
+// The handler is executed from the response body stream writer
+// with a copy of the request context: it may read the request,
+// user values, principal and Context from ctx, the response is not
+// sent by ctx. A returned error is sent as the last "error" event
+func (s *Server) SSE(p string, handler SSEHandler, opts ...RouteOption) *Server {
+	return s.Get(p, func(ctx *Ctx) {
+		ctx.SetHeader("Content-Type", "text/event-stream")
+		ctx.SetHeader("Cache-Control", "no-cache")
+		ctx.SetHeader("X-Accel-Buffering", "no")
+		es := &EventStream{
+			lastEventID: ctx.GetHeader("Last-Event-ID"),
+			done:        make(chan struct{}),
+		}
+		heartbeat := s.sseHeartbeat
+		// ctx is released while the stream is written
+		c := ctx.detach()
+		ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+			es.w = w
+			go es.heartbeat(heartbeat)
+			if err := handler(c, es); err != nil {
+				// report the error as the last event
+				b, _ := s.requestError(c, err).MarshalJSON()
+				es.WriteEvent(&Event{Name: "error", Data: b})
+			}
+			es.mu.Lock()
+			es.close(ErrStreamClosed)
+			es.mu.Unlock()
+		})
+	}, append([]RouteOption{noBatch(ErrBatchUnsupported)}, opts...)...)
+}
+
+// Events connects to an event stream at uri and calls fn for every
+// received event. When the connection drops, it reconnects after the
+// retry delay, sending the last received event id in Last-Event-ID.
+// It returns when fn returns an error (ErrStopEvents returns nil),
+// the connection fails, a line is longer than MaxSSELineSize
+// or the server responds with non 200 status
+func (c *Client) Events(uri string, fn func(*Event) error) error {
+	return c.Request().
+		SetMethod(MethodGet).
+		SetURI(uri).
+		Events(fn)
+}
+
+// Events executes the request as an event stream request,
+// see Client.Events
+func (r *Request) Events(fn func(*Event) error) error {
+	retry := DefaultSSERetry
+	for {
+		last, err := r.readEvents(&retry, fn)
+		if err == ErrStopEvents {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if last != "" {
+			r.SetHeader("Last-Event-ID", last)
+		}
+		time.Sleep(retry)
+	}
+}
+
+func (r *Request) readEvents(retry *time.Duration, fn func(*Event) error) (string, error) {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	r.prepare(req)
+	req.Header.Set("Accept", "text/event-stream")
+	res := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(res)
+	if err := r.httpClient(true).Do(req, res); err != nil {
+		return "", err
+	}
+	defer res.CloseBodyStream()
+	if res.StatusCode() == fasthttp.StatusNoContent {
+		// server asks the client to stop reconnecting
+		return "", ErrStopEvents
+	}
+	if res.StatusCode() != StatusOK {
+		return "", (&Response{res}).Error()
+	}
+	var (
+		last string
+		e    = new(Event)
+		sc   = bufio.NewScanner(res.BodyStream())
+	)
+	sc.Buffer(make([]byte, 4096), MaxSSELineSize)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			// dispatch the event
+			if e.Data != nil {
+				if e.ID != "" {
+					last = e.ID
+				}
+				if err := fn(e); err != nil {
+					return last, err
+				}
+			}
+			e = &Event{ID: e.ID}
+			continue
+		}
+		if line[0] == ':' {
+			continue
+		}
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "id":
+			e.ID = value
+		case "event":
+			e.Name = value
+		case "data":
+			if e.Data != nil {
+				e.Data = append(e.Data, '\n')
+			} else {
+				e.Data = []byte{}
+			}
+			e.Data = append(e.Data, value...)
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil {
+				*retry = time.Duration(ms) * time.Millisecond
+				e.Retry = *retry
+			}
+		}
+	}
+	// other read errors are dropped connections
+	if err := sc.Err(); err == bufio.ErrTooLong {
+		return last, err
+	}
+	return last, nil
+}
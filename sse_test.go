@@ -0,0 +1,149 @@
+package jsonapi
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestSSE(t *testing.T) {
+	suite.Run(t, new(SSETestSuite))
+}
+
+type SSETestSuite struct {
+	suite.Suite
+}
+
+func (t *SSETestSuite) listen(s *Server) *Client {
+	s.SetAddr("127.0.0.1:0")
+	t.NoError(s.newListener())
+	go s.Listen()
+	return NewClient(s.GetAddr())
+}
+
+func (t *SSETestSuite) TestEvents() {
+	s := NewServer()
+	s.SSE("/events", func(ctx *Ctx, es *EventStream) error {
+		// the request is copied before streaming
+		t.Equal("/events", string(ctx.Path()))
+		t.Equal("/events", ctx.Route())
+		t.Len(ctx.RequestID(), 32)
+		t.NoError(es.Retry(10 * time.Millisecond))
+		t.NoError(es.Comment("hello"))
+		from := 0
+		if id := es.LastEventID(); id != "" {
+			from, _ = strconv.Atoi(id)
+		}
+		for i := from + 1; i <= from+2; i++ {
+			t.NoError(es.SendID(strconv.Itoa(i), "tick", plainStruct{ID: i}))
+		}
+		return nil
+	})
+	c := t.listen(s)
+	defer s.ln.Close()
+	var events []*Event
+	err := c.Events("/events", func(e *Event) error {
+		events = append(events, e)
+		if len(events) == 4 {
+			return ErrStopEvents
+		}
+		return nil
+	})
+	t.NoError(err)
+	t.Len(events, 4)
+	for i, e := range events {
+		t.Equal(strconv.Itoa(i+1), e.ID)
+		t.Equal("tick", e.Name)
+		p := new(plainStruct)
+		t.NoError(e.ReadJSON(p))
+		t.Equal(i+1, p.ID)
+	}
+}
+
+func (t *SSETestSuite) TestLongLines() {
+	s := NewServer()
+	s.SSE("/events", func(ctx *Ctx, es *EventStream) error {
+		t.NoError(es.Send("long", strings.Repeat("a", 100000)))
+		return es.Send("too long", strings.Repeat("a", MaxSSELineSize))
+	})
+	c := t.listen(s)
+	defer s.ln.Close()
+	var events []*Event
+	err := c.Events("/events", func(e *Event) error {
+		events = append(events, e)
+		return nil
+	})
+	t.Equal(bufio.ErrTooLong, err)
+	t.Len(events, 1)
+	t.Len(events[0].Data, 100002)
+}
+
+func (t *SSETestSuite) TestHandlerError() {
+	s := NewServer()
+	s.SSE("/events", func(ctx *Ctx, es *EventStream) error {
+		if ctx.QueryArgs().Has("missing") {
+			return fmt.Errorf("load: %w", NewErrorString("animal not found", StatusNotFound))
+		}
+		return errors.New("db is down")
+	})
+	c := t.listen(s).WithContext(ContextWithRequestID(context.Background(), "sse-1"))
+	defer s.ln.Close()
+	stop := errors.New("stop")
+	for uri, data := range map[string]string{
+		"/events":         `{"error":"internal server error","code":500,"requestId":"sse-1"}`,
+		"/events?missing": `{"error":"animal not found","code":404,"requestId":"sse-1"}`,
+	} {
+		err := c.Events(uri, func(e *Event) error {
+			t.Equal("error", e.Name)
+			t.Equal(data, string(e.Data))
+			return stop
+		})
+		t.Equal(stop, err)
+	}
+}
+
+func (t *SSETestSuite) TestHeartbeat() {
+	s := NewServer().SetSSEHeartbeat(time.Millisecond)
+	s.SSE("/events", func(ctx *Ctx, es *EventStream) error {
+		time.Sleep(20 * time.Millisecond)
+		return es.Send("", StringResult("\"multi\nline\""))
+	})
+	c := t.listen(s)
+	defer s.ln.Close()
+	err := c.Events("/events", func(e *Event) error {
+		t.Equal("", e.Name)
+		t.Equal("\"multi\nline\"", string(e.Data))
+		return ErrStopEvents
+	})
+	t.NoError(err)
+}
+
+func (t *SSETestSuite) TestUnauthorized() {
+	s := NewServer()
+	s.SetAuthFunc(func(*Ctx) bool { return false })
+	s.SSE("/events", func(ctx *Ctx, es *EventStream) error {
+		return nil
+	})
+	c := t.listen(s)
+	defer s.ln.Close()
+	err := c.Events("/events", func(e *Event) error {
+		return nil
+	})
+	t.IsType(&Error{}, err)
+	t.Equal(StatusUnauthorized, err.(*Error).Code)
+}
+
+func (t *SSETestSuite) TestStreamClosed() {
+	es := &EventStream{done: make(chan struct{})}
+	es.close(ErrStreamClosed)
+	<-es.Done()
+	t.Equal(ErrStreamClosed, es.Send("a", plainStruct{}))
+	t.Equal(ErrStreamClosed, es.Comment("a"))
+}
@@ -0,0 +1,229 @@
+package jsonapi
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+)
+
+const (
+	// DefaultStreamFlushEvery is the default number of elements
+	// written to the connection between buffer flushes
+	DefaultStreamFlushEvery = 64
+)
+
+// StreamSource produces stream elements one by one.
+// Next should return io.EOF when there are no more elements,
+// any other error aborts the stream
+type StreamSource interface {
+	Next() (interface{}, error)
+}
+
+// StreamFunc is a func implementing StreamSource
+type StreamFunc func() (interface{}, error)
+
+// Next implements StreamSource
+func (f StreamFunc) Next() (interface{}, error) {
+	return f()
+}
+
+// StreamChan is a channel implementing StreamSource.
+// Closing the channel ends the stream, sending an error
+// value into the channel aborts it. Producers should stop
+// sending when StreamResult.Done is closed, for example:
+//
+//	select {
+//	case ch <- v:
+//	case <-res.Done():
+//		return
+//	}
+type StreamChan <-chan interface{}
+
+// Next implements StreamSource
+func (ch StreamChan) Next() (interface{}, error) {
+	v, ok := <-ch
+	if !ok {
+		return nil, io.EOF
+	}
+	if err, ok := v.(error); ok {
+		return nil, err
+	}
+	return v, nil
+}
+
+// NewStreamResult creates a result that is written to the
+// connection as a json array, element by element, as they are
+// produced by src
+func NewStreamResult(src StreamSource) *StreamResult {
+	return &StreamResult{
+		src:        src,
+		flushEvery: DefaultStreamFlushEvery,
+		done:       make(chan struct{}),
+		once:       new(sync.Once),
+	}
+}
+
+// NewNDJSONResult creates a result that is written to the
+// connection as newline delimited json, one element per line
+func NewNDJSONResult(src StreamSource) *StreamResult {
+	s := NewStreamResult(src)
+	s.ndjson = true
+	return s
+}
+
+// StreamResult is a streaming result. Unlike ListResult it never
+// holds the whole result in memory.
+//
+// Errors are mapped by server error mappers like controller errors.
+// If src fails before the first element, a regular error response
+// is returned. If it fails mid-stream, the status code is already
+// sent: a json array is left without the closing bracket, so clients
+// detect the truncated stream by the json decoding error of the body,
+// and ndjson gets a final Error line.
+type StreamResult struct {
+	src        StreamSource
+	ndjson     bool
+	flushEvery int
+	onError    func(error)
+	errorOf    func(error) *Error // converts errors of the request
+	done       chan struct{}
+	once       *sync.Once
+}
+
+// FlushEvery sets the number of elements written between
+// buffer flushes. n <= 1 flushes every element
+func (s *StreamResult) FlushEvery(n int) *StreamResult {
+	s.flushEvery = n
+	return s
+}
+
+// OnError sets a func called when src or marshaling fails
+func (s *StreamResult) OnError(fn func(error)) *StreamResult {
+	s.onError = fn
+	return s
+}
+
+// Done returns a channel that is closed when the stream is
+// written, aborted or the client has gone away
+func (s *StreamResult) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *StreamResult) finish() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// ContentType returns the response content type of the stream
+func (s *StreamResult) ContentType() string {
+	if s.ndjson {
+		return "application/x-ndjson"
+	}
+	return "application/json"
+}
+
+// MarshalJSON implements json.Marshaler by draining the whole
+// stream into memory. It allows a StreamResult to be used in places
+// where streaming is not possible
+func (s *StreamResult) MarshalJSON() ([]byte, error) {
+	defer s.finish()
+	v, err := s.src.Next()
+	if err != nil && err != io.EOF {
+		s.fail(err)
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	w := bufio.NewWriter(buf)
+	if err := s.write(w, v, err == io.EOF); err != nil {
+		return nil, err
+	}
+	w.Flush()
+	return buf.Bytes(), nil
+}
+
+// WriteStream writes the stream to the connection using
+// fasthttp body stream writer
+func (c *Ctx) WriteStream(s *StreamResult) {
+	// fetch the first element while the status can still be changed
+	first, err := s.src.Next()
+	if err != nil && err != io.EOF {
+		s.fail(err)
+		s.finish()
+		e := c.server().requestError(c, err)
+		c.Err(e, e.Code)
+		return
+	}
+	c.SetHeader("Content-Type", s.ContentType())
+	done := err == io.EOF
+	// ctx is released while the stream is written
+	srv, requestID := c.server(), c.RequestID()
+	s.errorOf = func(err error) *Error {
+		return srv.errorWithID(err, requestID)
+	}
+	c.SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer s.finish()
+		s.write(w, first, done)
+	})
+}
+
+// write writes v and the rest of src to w, the returned error
+// is already reported to onError
+func (s *StreamResult) write(w *bufio.Writer, v interface{}, done bool) error {
+	if !s.ndjson {
+		w.WriteByte('[')
+	}
+	for n := 0; !done; n++ {
+		b, err := Marshal(v)
+		if err != nil {
+			s.abort(w, err)
+			return err
+		}
+		if n > 0 && !s.ndjson {
+			w.WriteByte(',')
+		}
+		w.Write(b)
+		if s.ndjson {
+			w.WriteByte('\n')
+		}
+		if s.flushEvery <= 1 || (n+1)%s.flushEvery == 0 {
+			if err := w.Flush(); err != nil {
+				// client has gone away
+				s.fail(err)
+				return err
+			}
+		}
+		v, err = s.src.Next()
+		if err == io.EOF {
+			done = true
+		} else if err != nil {
+			s.abort(w, err)
+			return err
+		}
+	}
+	if !s.ndjson {
+		w.WriteByte(']')
+	}
+	return nil
+}
+
+func (s *StreamResult) abort(w *bufio.Writer, err error) {
+	s.fail(err)
+	if s.ndjson {
+		var e *Error
+		if s.errorOf != nil {
+			e = s.errorOf(err)
+		} else {
+			// not written for a server request
+			e = (*Server)(nil).mapError(err)
+		}
+		b, _ := e.MarshalJSON()
+		w.Write(b)
+		w.WriteByte('\n')
+	}
+}
+
+func (s *StreamResult) fail(err error) {
+	if s.onError != nil {
+		s.onError(err)
+	}
+}
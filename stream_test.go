@@ -0,0 +1,173 @@
+package jsonapi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestStream(t *testing.T) {
+	suite.Run(t, new(StreamTestSuite))
+}
+
+type StreamTestSuite struct {
+	suite.Suite
+}
+
+func (t *StreamTestSuite) source(n int, err error) StreamSource {
+	i := 0
+	return StreamFunc(func() (interface{}, error) {
+		if i == n {
+			if err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		i++
+		return plainStruct{ID: i}, nil
+	})
+}
+
+func (t *StreamTestSuite) chanSource(v ...interface{}) StreamSource {
+	ch := make(chan interface{}, len(v))
+	for _, i := range v {
+		ch <- i
+	}
+	close(ch)
+	return StreamChan(ch)
+}
+
+func (t *StreamTestSuite) serve(res *Result) (int, string, string) {
+	ln, s := new(ServerTestSuite).getServer()
+	s.ControllerMethod(MethodGet, "/a1", func(*Ctx) *Result {
+		return res
+	})
+	go s.Listen()
+	defer ln.Close()
+	_, rs, err := new(ServerTestSuite).request(ln, MethodGet)
+	t.NoError(err)
+	return rs.StatusCode(), string(rs.Header.ContentType()), string(rs.Body())
+}
+
+func (t *StreamTestSuite) done(s *StreamResult) {
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second):
+		t.Fail("stream is not done")
+	}
+}
+
+func (t *StreamTestSuite) TestStreamResult() {
+	c := new(BaseController)
+	code, ct, body := t.serve(c.OKStream(t.source(3, nil)))
+	t.Equal(StatusOK, code)
+	t.Equal("application/json", ct)
+	t.Equal(`[{"id":1,"name":""},{"id":2,"name":""},{"id":3,"name":""}]`, body)
+	_, _, body = t.serve(c.OKStream(t.source(0, nil)))
+	t.Equal(`[]`, body)
+	_, _, body = t.serve(c.OKStream(t.chanSource(StringResult(`"a"`), plainStruct{ID: 1})))
+	t.Equal(`["a",{"id":1,"name":""}]`, body)
+}
+
+func (t *StreamTestSuite) TestNDJSONResult() {
+	c := new(BaseController)
+	code, ct, body := t.serve(c.OKNDJSON(t.source(2, nil)))
+	t.Equal(StatusOK, code)
+	t.Equal("application/x-ndjson", ct)
+	t.Equal("{\"id\":1,\"name\":\"\"}\n{\"id\":2,\"name\":\"\"}\n", body)
+}
+
+func (t *StreamTestSuite) TestStreamErrorBeforeFirstElement() {
+	var reported error
+	res := &Result{Data: NewStreamResult(t.source(0, errors.New("db is down"))).OnError(func(err error) {
+		reported = err
+	})}
+	code, _, body := t.serve(res)
+	t.Equal(StatusInternalServerError, code)
+	e := new(Error)
+	t.NoError(e.UnmarshalJSON([]byte(body)))
+	t.Equal(ErrInternal.Error(), e.Err)
+	t.Equal(StatusInternalServerError, e.Code)
+	t.Len(e.RequestID, 32)
+	t.EqualError(reported, "db is down")
+	t.done(res.Data.(*StreamResult))
+
+	code, _, body = t.serve(&Result{Data: NewStreamResult(t.source(0, NewErrorString("animal not found", StatusNotFound)))})
+	t.Equal(StatusNotFound, code)
+	t.Contains(body, `"error":"animal not found"`)
+}
+
+func (t *StreamTestSuite) TestStreamErrorMidStream() {
+	var reported error
+	res := &Result{Data: NewStreamResult(t.source(2, errors.New("db is down"))).FlushEvery(1).OnError(func(err error) {
+		reported = err
+	})}
+	code, _, body := t.serve(res)
+	t.Equal(StatusOK, code)
+	t.Equal(`[{"id":1,"name":""},{"id":2,"name":""}`, body)
+	t.EqualError(reported, "db is down")
+	t.done(res.Data.(*StreamResult))
+
+	// abort lines hide unmapped errors
+	for src, line := range map[StreamSource]string{
+		t.chanSource(plainStruct{ID: 1}, errors.New("db is down")):                            `{"error":"internal server error","code":500,"requestId":"`,
+		t.chanSource(plainStruct{ID: 1}, make(chan int)):                                      `{"error":"internal server error","code":500,"requestId":"`,
+		t.chanSource(plainStruct{ID: 1}, fmt.Errorf("load: %w", NewErrorString("gone", 410))): `{"error":"gone","code":410,"requestId":"`,
+	} {
+		_, _, body = t.serve(&Result{Data: NewNDJSONResult(src)})
+		t.True(strings.HasPrefix(body, "{\"id\":1,\"name\":\"\"}\n"+line), body)
+	}
+}
+
+func (t *StreamTestSuite) TestStreamChanDone() {
+	ch := make(chan interface{})
+	res := NewStreamResult(StreamChan(ch)).FlushEvery(1)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for i := 1; ; i++ {
+			select {
+			case ch <- plainStruct{ID: i}:
+			case <-res.Done():
+				return
+			}
+		}
+	}()
+	ln, s := new(ServerTestSuite).getServer()
+	s.ControllerMethod(MethodGet, "/a1", func(*Ctx) *Result {
+		return &Result{Data: res}
+	})
+	go s.Listen()
+	defer ln.Close()
+	conn, err := ln.Dial()
+	t.NoError(err)
+	_, err = conn.Write([]byte("GET /a1 HTTP/1.1\r\nHost: a\r\n\r\n"))
+	t.NoError(err)
+	_, err = conn.Read(make([]byte, 1024))
+	t.NoError(err)
+	// the producer stops when the client goes away
+	conn.Close()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fail("producer is not stopped")
+	}
+}
+
+func (t *StreamTestSuite) TestMarshalJSON() {
+	b, err := NewStreamResult(t.source(2, nil)).MarshalJSON()
+	t.NoError(err)
+	t.Equal(`[{"id":1,"name":""},{"id":2,"name":""}]`, string(b))
+	b, err = NewNDJSONResult(t.source(1, nil)).MarshalJSON()
+	t.NoError(err)
+	t.Equal("{\"id\":1,\"name\":\"\"}\n", string(b))
+	_, err = NewStreamResult(t.source(1, errors.New("fail"))).MarshalJSON()
+	t.EqualError(err, "fail")
+	_, err = NewStreamResult(t.source(0, errors.New("fail"))).MarshalJSON()
+	t.EqualError(err, "fail")
+}
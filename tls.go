@@ -0,0 +1,188 @@
+package jsonapi
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+
+	"github.com/valyala/fasthttp"
+)
+
+var (
+	ErrNoCertificates = errors.New("no certificates found")
+)
+
+// LoadCertPool returns a pool of PEM encoded certificates of files
+func LoadCertPool(files ...string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, ErrNoCertificates
+		}
+	}
+	return pool, nil
+}
+
+// SetTLSConfig sets the TLS config of ListenTLS, ListenTLSEmbed and
+// ListenTLSConfig. Certificates passed to ListenTLS and ListenTLSEmbed
+// are added to a copy of cfg
+func (s *Server) SetTLSConfig(cfg *tls.Config) *Server {
+	s.tlsConfig = cfg
+	return s
+}
+
+// SetClientCAs enables client certificate (mutual TLS) verification,
+// client certificates are verified with pool according to mode,
+// for example: tls.RequireAndVerifyClientCert
+func (s *Server) SetClientCAs(pool *x509.CertPool, mode tls.ClientAuthType) *Server {
+	if s.tlsConfig == nil {
+		s.tlsConfig = &tls.Config{}
+	}
+	s.tlsConfig.ClientCAs = pool
+	s.tlsConfig.ClientAuth = mode
+	return s
+}
+
+// ListenTLSConfig starts http server and listens on defined addr with
+// the TLS config set by SetTLSConfig, the config must provide certificates
+func (s *Server) ListenTLSConfig() error {
+	if s.tlsConfig == nil {
+		return ErrNoCertificates
+	}
+	return s.serveTLS(s.tlsConfig)
+}
+
+// serveTLS serves TLS connections with a copy of the
+// server TLS config including certs
+func (s *Server) serveTLS(cfg *tls.Config, certs ...tls.Certificate) error {
+	if err := s.Err(); err != nil {
+		return err
+	}
+	cfg = cfg.Clone()
+	cfg.Certificates = append(cfg.Certificates, certs...)
+	if len(cfg.Certificates) == 0 && cfg.GetCertificate == nil && cfg.GetConfigForClient == nil {
+		return ErrNoCertificates
+	}
+	if err := s.newListener(); err != nil {
+		return err
+	}
+	return fasthttp.Serve(tls.NewListener(s.ln, cfg), s.router.Handler)
+}
+
+// CertIdentity is the identity of a verified client certificate
+type CertIdentity struct {
+	CommonName         string
+	Organization       []string
+	OrganizationalUnit []string
+	DNSNames           []string
+	EmailAddresses     []string
+	URIs               []string // for example: SPIFFE ids
+	SerialNumber       string
+	Fingerprint        string // hex encoded SHA-256 of the certificate
+}
+
+// ClientCert returns the verified client certificate
+// of a TLS request or nil
+func (c *Ctx) ClientCert() *x509.Certificate {
+	state := c.TLSConnectionState()
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
+
+// ClientCertIdentity returns the identity of the verified
+// client certificate of a TLS request or nil
+func (c *Ctx) ClientCertIdentity() *CertIdentity {
+	cert := c.ClientCert()
+	if cert == nil {
+		return nil
+	}
+	sum := sha256.Sum256(cert.Raw)
+	id := &CertIdentity{
+		CommonName:         cert.Subject.CommonName,
+		Organization:       cert.Subject.Organization,
+		OrganizationalUnit: cert.Subject.OrganizationalUnit,
+		DNSNames:           cert.DNSNames,
+		EmailAddresses:     cert.EmailAddresses,
+		SerialNumber:       cert.SerialNumber.String(),
+		Fingerprint:        hex.EncodeToString(sum[:]),
+	}
+	for _, u := range cert.URIs {
+		id.URIs = append(id.URIs, u.String())
+	}
+	return id
+}
+
+// ClientCertAuth returns an Authenticator of requests with verified
+// client certificates. The principal subject is the first URI SAN or
+// the common name, roles are organizational units of the certificate
+func ClientCertAuth() Authenticator {
+	return AuthenticatorFunc(func(ctx *Ctx) (*Principal, error) {
+		id := ctx.ClientCertIdentity()
+		if id == nil {
+			return nil, ErrUnauthorized
+		}
+		subject := id.CommonName
+		if len(id.URIs) > 0 {
+			subject = id.URIs[0]
+		}
+		return &Principal{
+			Subject: subject,
+			Method:  "mtls",
+			Roles:   id.OrganizationalUnit,
+			Claims: map[string]interface{}{
+				"cn":          id.CommonName,
+				"dns":         id.DNSNames,
+				"uris":        id.URIs,
+				"fingerprint": id.Fingerprint,
+			},
+		}, nil
+	})
+}
+
+// SetTLSConfig sets the TLS config of client requests
+// and forces the client to use https protocol
+func (c *Client) SetTLSConfig(cfg *tls.Config) *Client {
+	c.tlsConfig = cfg
+	c.useSSL = true
+	c.resetHTTPClient()
+	return c
+}
+
+// SetClientCert sets the client certificate sent to servers
+// requiring mutual TLS
+func (c *Client) SetClientCert(cert tls.Certificate) *Client {
+	cfg := c.cloneTLSConfig()
+	cfg.Certificates = []tls.Certificate{cert}
+	return c.SetTLSConfig(cfg)
+}
+
+// SetRootCAs sets root CAs used to verify server certificates
+func (c *Client) SetRootCAs(pool *x509.CertPool) *Client {
+	cfg := c.cloneTLSConfig()
+	cfg.RootCAs = pool
+	return c.SetTLSConfig(cfg)
+}
+
+// SetServerName sets the server name used to verify server
+// certificates, it defaults to the host of the client addr
+func (c *Client) SetServerName(name string) *Client {
+	cfg := c.cloneTLSConfig()
+	cfg.ServerName = name
+	return c.SetTLSConfig(cfg)
+}
+
+func (c *Client) cloneTLSConfig() *tls.Config {
+	if c.tlsConfig == nil {
+		return &tls.Config{}
+	}
+	return c.tlsConfig.Clone()
+}
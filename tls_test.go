@@ -0,0 +1,190 @@
+package jsonapi
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestTLS(t *testing.T) {
+	suite.Run(t, new(TLSTestSuite))
+}
+
+type TLSTestSuite struct {
+	suite.Suite
+	ca     *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	pool   *x509.CertPool
+	server tls.Certificate
+	client tls.Certificate
+}
+
+func (t *TLSTestSuite) SetupSuite() {
+	var err error
+	t.caKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	t.NoError(err)
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &t.caKey.PublicKey, t.caKey)
+	t.NoError(err)
+	t.ca, err = x509.ParseCertificate(der)
+	t.NoError(err)
+	t.pool = x509.NewCertPool()
+	t.pool.AddCert(t.ca)
+
+	t.server = t.issue(&x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "server"},
+		DNSNames:     []string{"jsonapi.test"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	spiffe, _ := url.Parse("spiffe://example.org/billing")
+	t.client = t.issue(&x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "billing", OrganizationalUnit: []string{"payments"}},
+		DNSNames:     []string{"billing.internal"},
+		URIs:         []*url.URL{spiffe},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+}
+
+func (t *TLSTestSuite) issue(tpl *x509.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	t.NoError(err)
+	tpl.NotBefore = time.Now().Add(-time.Hour)
+	tpl.NotAfter = time.Now().Add(time.Hour)
+	tpl.KeyUsage = x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, tpl, t.ca, &key.PublicKey, t.caKey)
+	t.NoError(err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func (t *TLSTestSuite) serve(s *Server) (string, func()) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	t.NoError(err)
+	s.SetListener(ln).
+		SetTLSConfig(&tls.Config{Certificates: []tls.Certificate{t.server}}).
+		SetClientCAs(t.pool, tls.VerifyClientCertIfGiven)
+	go s.ListenTLSConfig()
+	return ln.Addr().String(), func() { ln.Close() }
+}
+
+func (t *TLSTestSuite) TestClientCertIdentity() {
+	addr, closeFn := t.serve(NewServer().
+		Get("/me", func(ctx *Ctx) {
+			id := ctx.ClientCertIdentity()
+			if id == nil {
+				ctx.OK("anonymous")
+				return
+			}
+			ctx.OK(id.CommonName + " " + id.URIs[0] + " " + id.DNSNames[0])
+		}))
+	defer closeFn()
+
+	cl := NewClient(addr).SetRootCAs(t.pool).SetClientCert(t.client)
+	rs, err := cl.Get("/me")
+	t.NoError(err)
+	t.Equal(`"billing spiffe://example.org/billing billing.internal"`, string(rs.Body()))
+
+	rs, err = NewClient(addr).SetRootCAs(t.pool).Get("/me")
+	t.NoError(err)
+	t.Equal(`"anonymous"`, string(rs.Body()))
+
+	// server name of the certificate
+	rs, err = NewClient(addr).SetRootCAs(t.pool).SetServerName("jsonapi.test").Get("/me")
+	t.NoError(err)
+	t.Equal(StatusOK, rs.StatusCode())
+
+	// unknown root CAs
+	_, err = NewClient(addr).SetTLSConfig(&tls.Config{}).Get("/me")
+	t.Error(err)
+	_, err = NewClient(addr).SetRootCAs(t.pool).SetServerName("other.test").Get("/me")
+	t.Error(err)
+}
+
+func (t *TLSTestSuite) TestClientCertAuth() {
+	addr, closeFn := t.serve(NewServer().SetAuthenticator(ClientCertAuth()).
+		Get("/payments", func(ctx *Ctx) { ctx.OK(ctx.Principal().Subject) }, RequireRoles("payments")).
+		Batch("/batch"))
+	defer closeFn()
+
+	rs, err := NewClient(addr).SetRootCAs(t.pool).SetClientCert(t.client).Get("/payments")
+	t.NoError(err)
+	t.Equal(StatusOK, rs.StatusCode())
+	t.Equal(`"spiffe://example.org/billing"`, string(rs.Body()))
+
+	// batch operations keep the client certificate
+	rs, err = NewClient(addr).SetRootCAs(t.pool).SetClientCert(t.client).
+		Post("/batch", BytesResult(`[{"path":"/payments"}]`))
+	t.NoError(err)
+	var res BatchResults
+	t.NoError(rs.ReadJSON(&res))
+	t.Len(res, 1)
+	t.Equal(StatusOK, res[0].Status)
+	t.Equal(`"spiffe://example.org/billing"`, string(res[0].Body))
+
+	rs, err = NewClient(addr).SetRootCAs(t.pool).Get("/payments")
+	t.NoError(err)
+	t.Equal(StatusUnauthorized, rs.StatusCode())
+}
+
+func (t *TLSTestSuite) TestRequireClientCert() {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	t.NoError(err)
+	defer ln.Close()
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: t.server.Certificate[0]})
+	keyDER, err := x509.MarshalECPrivateKey(t.server.PrivateKey.(*ecdsa.PrivateKey))
+	t.NoError(err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	s := NewServer().SetListener(ln).
+		SetClientCAs(t.pool, tls.RequireAndVerifyClientCert).
+		Get("/ping", func(ctx *Ctx) { ctx.OK("pong") })
+	go s.ListenTLSEmbed(certPEM, keyPEM)
+	addr := ln.Addr().String()
+
+	rs, err := NewClient(addr).SetRootCAs(t.pool).SetClientCert(t.client).Get("/ping")
+	t.NoError(err)
+	t.Equal(StatusOK, rs.StatusCode())
+	_, err = NewClient(addr).SetRootCAs(t.pool).Get("/ping")
+	t.Error(err)
+}
+
+func (t *TLSTestSuite) TestLoadCertPool() {
+	dir, err := ioutil.TempDir("", "certs")
+	t.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ca.pem")
+	t.NoError(ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: t.ca.Raw}), 0600))
+	pool, err := LoadCertPool(path)
+	t.NoError(err)
+	t.True(pool.Equal(t.pool))
+
+	t.NoError(ioutil.WriteFile(path, []byte("not a certificate"), 0600))
+	_, err = LoadCertPool(path)
+	t.Equal(ErrNoCertificates, err)
+	_, err = LoadCertPool(filepath.Join(dir, "missing.pem"))
+	t.Error(err)
+	t.Equal(ErrNoCertificates, NewServer().ListenTLSConfig())
+}
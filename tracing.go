@@ -0,0 +1,282 @@
+package jsonapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// TraceParentHeader is the W3C trace context header
+	TraceParentHeader = "traceparent"
+
+	// TraceStateHeader is the W3C trace state header
+	TraceStateHeader = "tracestate"
+
+	SpanKindServer = "server"
+	SpanKindClient = "client"
+
+	spanKey = "jsonapi.span"
+)
+
+var (
+	ErrInvalidTraceParent = errors.New("invalid traceparent")
+)
+
+// TraceID is a W3C trace id
+type TraceID [16]byte
+
+// String returns the trace id as hex
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID is a W3C span id
+type SpanID [8]byte
+
+// String returns the span id as hex
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanContext identifies a span across services
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string // vendor specific tracestate, propagated as is
+}
+
+// IsValid returns TRUE if trace and span ids are not zero
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// TraceParent returns the span context as traceparent header value
+func (sc SpanContext) TraceParent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceParent parses a traceparent header value
+func ParseTraceParent(s string) (SpanContext, error) {
+	var sc SpanContext
+	// version-traceid-spanid-flags
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' || s[:2] == "ff" {
+		return sc, ErrInvalidTraceParent
+	}
+	if s[:2] == "00" && len(s) != 55 {
+		return sc, ErrInvalidTraceParent
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(s[3:35])); err != nil {
+		return sc, ErrInvalidTraceParent
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(s[36:52])); err != nil {
+		return sc, ErrInvalidTraceParent
+	}
+	flags, err := strconv.ParseUint(s[53:55], 16, 8)
+	if err != nil || !sc.IsValid() {
+		return sc, ErrInvalidTraceParent
+	}
+	sc.Sampled = flags&1 == 1
+	return sc, nil
+}
+
+// Span is a traced operation, spans are started by Server
+// for every request and by Client for every call
+type Span struct {
+	Name       string
+	Kind       string // SpanKindServer or SpanKindClient
+	Service    string
+	Context    SpanContext
+	Parent     SpanID // zero for root spans
+	Start      time.Time
+	End        time.Time
+	Status     int    // http status, 0 if the request failed
+	Err        string // error message of a failed request
+	Attributes map[string]string
+
+	mu *sync.Mutex
+}
+
+// SetAttribute sets a span attribute
+func (s *Span) SetAttribute(k string, v string) {
+	s.mu.Lock()
+	s.Attributes[k] = v
+	s.mu.Unlock()
+}
+
+// Attribute returns a span attribute
+func (s *Span) Attribute(k string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Attributes[k]
+}
+
+// SpanExporter receives ended sampled spans
+type SpanExporter interface {
+	Export(s *Span)
+}
+
+// NewInMemoryExporter creates a SpanExporter keeping spans in memory
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{mu: new(sync.Mutex)}
+}
+
+// InMemoryExporter is a SpanExporter keeping spans in memory,
+// it is useful in tests
+type InMemoryExporter struct {
+	spans []*Span
+	mu    *sync.Mutex
+}
+
+// Export implements SpanExporter
+func (e *InMemoryExporter) Export(s *Span) {
+	e.mu.Lock()
+	e.spans = append(e.spans, s)
+	e.mu.Unlock()
+}
+
+// Spans returns exported spans in order of export
+func (e *InMemoryExporter) Spans() []*Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*Span{}, e.spans...)
+}
+
+// Reset removes exported spans
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	e.spans = nil
+	e.mu.Unlock()
+}
+
+// NewTracer creates a tracer of service exporting spans to exporter
+func NewTracer(service string, exporter SpanExporter) *Tracer {
+	return &Tracer{service: service, exporter: exporter}
+}
+
+// Tracer starts and exports spans
+type Tracer struct {
+	service  string
+	exporter SpanExporter
+}
+
+// start starts a span, child of parent if parent is valid
+func (t *Tracer) start(name string, kind string, parent SpanContext) *Span {
+	s := &Span{
+		Name:       name,
+		Kind:       kind,
+		Service:    t.service,
+		Start:      time.Now(),
+		Attributes: map[string]string{},
+		mu:         new(sync.Mutex),
+	}
+	if parent.IsValid() {
+		s.Context = parent
+		s.Parent = parent.SpanID
+	} else {
+		rand.Read(s.Context.TraceID[:])
+		s.Context.Sampled = true
+	}
+	rand.Read(s.Context.SpanID[:])
+	return s
+}
+
+// end ends s and exports it if it is sampled
+func (t *Tracer) end(s *Span, status int, err error) {
+	s.mu.Lock()
+	s.End = time.Now()
+	s.Status = status
+	if err != nil {
+		s.Err = err.Error()
+	}
+	s.mu.Unlock()
+	if s.Context.Sampled && t.exporter != nil {
+		t.exporter.Export(s)
+	}
+}
+
+// SetTracer enables tracing of requests served by routes. The span
+// context is extracted from W3C trace context headers and a server
+// span named by method and route pattern is started per request
+func (s *Server) SetTracer(t *Tracer) *Server {
+	s.tracer = t
+	return s
+}
+
+// startSpan starts the server span of c
+func (s *Server) startSpan(c *Ctx, rt *route) *Span {
+	parent, _ := ParseTraceParent(c.GetHeader(TraceParentHeader))
+	parent.TraceState = c.GetHeader(TraceStateHeader)
+	span := s.tracer.start(rt.method+" "+rt.path, SpanKindServer, parent)
+	span.Attributes["http.method"] = rt.method
+	span.Attributes["http.route"] = rt.path
+	span.Attributes["http.target"] = string(c.RequestURI())
+	span.Attributes["request.id"] = c.RequestID()
+	c.SetUserValue(spanKey, span)
+	return span
+}
+
+// Span returns the server span of the request or nil
+// if tracing is disabled
+func (c *Ctx) Span() *Span {
+	s, _ := c.UserValue(spanKey).(*Span)
+	return s
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying span, a Client
+// called with this context propagates the span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// SpanFromContext returns the span carried by ctx or nil
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanContextKey{}).(*Span)
+	return s
+}
+
+// SetTracer enables tracing of client requests, a client span
+// child of the span carried by the client context is started per
+// request. Without a tracer the context span is propagated as is
+func (c *Client) SetTracer(t *Tracer) *Client {
+	c.tracer = t
+	return c
+}
+
+// startSpan starts the client span of r and injects
+// the span context into r headers
+func (r *Request) startSpan() *Span {
+	var parent *Span
+	if r.ctx != nil {
+		parent = SpanFromContext(r.ctx)
+	}
+	sc := SpanContext{}
+	if parent != nil {
+		sc = parent.Context
+	}
+	var span *Span
+	if r.tracer != nil {
+		span = r.tracer.start(r.method+" "+r.host, SpanKindClient, sc)
+		span.Attributes["http.method"] = r.method
+		span.Attributes["http.url"] = r.makeURL()
+		sc = span.Context
+	}
+	if sc.IsValid() {
+		r.SetHeader(TraceParentHeader, sc.TraceParent())
+		if sc.TraceState != "" {
+			r.SetHeader(TraceStateHeader, sc.TraceState)
+		}
+	}
+	return span
+}
@@ -0,0 +1,113 @@
+package jsonapi
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestTracing(t *testing.T) {
+	suite.Run(t, new(TracingTestSuite))
+}
+
+type TracingTestSuite struct {
+	suite.Suite
+}
+
+func (t *TracingTestSuite) TestParseTraceParent() {
+	sc, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	t.NoError(err)
+	t.Equal("4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	t.Equal("00f067aa0ba902b7", sc.SpanID.String())
+	t.True(sc.Sampled)
+	t.Equal("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.TraceParent())
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-xx",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-zzf067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+	} {
+		_, err := ParseTraceParent(s)
+		t.Equal(ErrInvalidTraceParent, err, s)
+	}
+}
+
+func (t *TracingTestSuite) listen(s *Server) (*Client, func()) {
+	ln := fasthttputil.NewInmemoryListener()
+	go s.SetListener(ln).Listen()
+	return NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }), func() { ln.Close() }
+}
+
+func (t *TracingTestSuite) TestPropagation() {
+	exp := NewInMemoryExporter()
+	upstream := NewServer().
+		SetTracer(NewTracer("upstream", exp)).
+		Get("/animals/:id", func(ctx *Ctx) { ctx.OK(ctx.GetParamString("id")) })
+	upCl, closeUp := t.listen(upstream)
+	defer closeUp()
+	upCl.SetTracer(NewTracer("gateway", exp))
+	gateway := NewServer().
+		SetTracer(NewTracer("gateway", exp)).
+		Get("/proxy/:id", func(ctx *Ctx) {
+			ctx.Span().SetAttribute("animal.id", ctx.GetParamString("id"))
+			rs, err := upCl.WithContext(ctx.Context()).Get("/animals/" + ctx.GetParamString("id"))
+			if err != nil {
+				ctx.ErrBadGateway(err)
+				return
+			}
+			ctx.SetBody(rs.Body())
+		})
+	cl, closeFn := t.listen(gateway)
+	defer closeFn()
+
+	rs, err := cl.Request().SetMethod(MethodGet).SetURI("/proxy/1").
+		SetHeader(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01").
+		SetHeader(TraceStateHeader, "vendor=1").Do()
+	t.NoError(err)
+	t.Equal(`"1"`, string(rs.Body()))
+
+	spans := exp.Spans()
+	t.Len(spans, 3)
+	server, client, gw := spans[0], spans[1], spans[2]
+	t.Equal("GET /animals/:id", server.Name)
+	t.Equal(SpanKindServer, server.Kind)
+	t.Equal("upstream", server.Service)
+	t.Equal(SpanKindClient, client.Kind)
+	t.Equal("GET /proxy/:id", gw.Name)
+	t.Equal("1", gw.Attribute("animal.id"))
+	t.Equal("00f067aa0ba902b7", gw.Parent.String())
+	t.Equal(gw.Context.SpanID, client.Parent)
+	t.Equal(client.Context.SpanID, server.Parent)
+	for _, s := range spans {
+		t.Equal("4bf92f3577b34da6a3ce929d0e0e4736", s.Context.TraceID.String())
+		t.Equal("vendor=1", s.Context.TraceState)
+		t.Equal(StatusOK, s.Status)
+		t.False(s.End.Before(s.Start))
+	}
+}
+
+func (t *TracingTestSuite) TestRootAndUnsampled() {
+	exp := NewInMemoryExporter()
+	s := NewServer().
+		SetTracer(NewTracer("api", exp)).
+		Get("/a1", func(ctx *Ctx) { ctx.OK(ctx.Span().Context.TraceParent()) })
+	cl, closeFn := t.listen(s)
+	defer closeFn()
+	_, err := cl.Get("/a1")
+	t.NoError(err)
+	spans := exp.Spans()
+	t.Len(spans, 1)
+	t.True(spans[0].Context.IsValid())
+	t.Equal(SpanID{}, spans[0].Parent)
+
+	exp.Reset()
+	_, err = cl.Request().SetMethod(MethodGet).SetURI("/a1").
+		SetHeader(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00").Do()
+	t.NoError(err)
+	t.Empty(exp.Spans())
+}
@@ -0,0 +1,204 @@
+package jsonapi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Validator is an optional interface of Typed handler input,
+// Validate is called after the input is decoded and bound
+type Validator interface {
+	Validate() error
+}
+
+var (
+	ctxType   = reflect.TypeOf((*Ctx)(nil))
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Typed adapts fn to a ControllerHandler. fn must be one of:
+//
+//	func(*Ctx, *In) (Out, error)
+//	func(*Ctx) (Out, error)
+//
+// In is decoded from the request body (if not empty), then fields
+// tagged with `param:"name"` are set from path parameters and fields
+// tagged with `query:"name"` from query arguments. If In implements
+// Validator it is validated. Decode, bind and validation errors are
+// mapped by server error mappers, unmapped errors are returned
+// with StatusBadRequest.
+// Out is encoded as the response (nil as null), a returned *Error keeps its code
+// and other errors are mapped by server error mappers.
+// Typed panics if fn has a different signature
+func Typed(fn interface{}) ControllerHandler {
+	v := reflect.ValueOf(fn)
+	if !v.IsValid() {
+		panic("jsonapi: typed handler is nil")
+	}
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumIn() < 1 || t.NumIn() > 2 ||
+		t.In(0) != ctxType || t.NumOut() != 2 || t.Out(1) != errorType {
+		panic(fmt.Sprintf("jsonapi: invalid typed handler %s", t))
+	}
+	var in reflect.Type
+	var bindings []binding
+	if t.NumIn() == 2 {
+		in = t.In(1)
+		if in.Kind() != reflect.Ptr {
+			panic(fmt.Sprintf("jsonapi: typed handler input must be a pointer, got %s", in))
+		}
+		bindings = newBindings(in.Elem(), nil)
+	}
+	return func(ctx *Ctx) *Result {
+		args := []reflect.Value{reflect.ValueOf(ctx)}
+		if in != nil {
+			arg := reflect.New(in.Elem())
+			if err := decodeTyped(ctx, arg, bindings); err != nil {
+				return &Result{Err: ctx.server().mapError(err, StatusBadRequest)}
+			}
+			args = append(args, arg)
+		}
+		out := v.Call(args)
+		if err, _ := out[1].Interface().(error); err != nil {
+			return &Result{Cause: err}
+		}
+		if isNil(out[0]) {
+			// nil outputs are encoded as null
+			return &Result{}
+		}
+		return &Result{Data: out[0].Interface()}
+	}
+}
+
+// isNil returns TRUE if v is nil or a nil pointer, map,
+// slice, interface, func or chan
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
+
+func decodeTyped(ctx *Ctx, arg reflect.Value, bindings []binding) error {
+	if len(ctx.PostBody()) > 0 {
+		if err := ctx.ReadJSON(arg.Interface()); err != nil {
+			return err
+		}
+	}
+	for _, b := range bindings {
+		if err := b.bind(ctx, arg.Elem().FieldByIndex(b.index)); err != nil {
+			return err
+		}
+	}
+	if v, ok := arg.Interface().(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// binding links a struct field to a path parameter or query argument
+type binding struct {
+	index []int
+	param string
+	query string
+}
+
+func newBindings(t reflect.Type, index []int) []binding {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var res []binding
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		idx := append(append([]int{}, index...), i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			res = append(res, newBindings(f.Type, idx)...)
+			continue
+		}
+		b := binding{index: idx, param: f.Tag.Get("param"), query: f.Tag.Get("query")}
+		if b.param == "" && b.query == "" {
+			continue
+		}
+		if f.PkgPath != "" || !bindable(f.Type) {
+			panic(fmt.Sprintf("jsonapi: field %s of %s can't be bound", f.Name, t))
+		}
+		res = append(res, b)
+	}
+	return res
+}
+
+func bindable(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func (b binding) bind(ctx *Ctx, f reflect.Value) error {
+	var values []string
+	name := b.param
+	if b.param != "" {
+		if s, ok := ctx.UserValue(b.param).(string); ok {
+			values = []string{s}
+		}
+	} else {
+		name = b.query
+		for _, v := range ctx.QueryArgs().PeekMulti(b.query) {
+			values = append(values, string(v))
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	if f.Kind() != reflect.Slice {
+		return setValue(f, name, values[0])
+	}
+	sl := reflect.MakeSlice(f.Type(), len(values), len(values))
+	for i, s := range values {
+		if err := setValue(sl.Index(i), name, s); err != nil {
+			return err
+		}
+	}
+	f.Set(sl)
+	return nil
+}
+
+func setValue(f reflect.Value, name string, s string) error {
+	var err error
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		var v bool
+		v, err = strconv.ParseBool(s)
+		f.SetBool(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var v int64
+		v, err = strconv.ParseInt(s, 10, f.Type().Bits())
+		f.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var v uint64
+		v, err = strconv.ParseUint(s, 10, f.Type().Bits())
+		f.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		var v float64
+		v, err = strconv.ParseFloat(s, f.Type().Bits())
+		f.SetFloat(v)
+	}
+	if err != nil {
+		return errors.New("invalid value of " + name + ": " + s)
+	}
+	return nil
+}
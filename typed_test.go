@@ -0,0 +1,142 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestTyped(t *testing.T) {
+	suite.Run(t, new(TypedTestSuite))
+}
+
+type TypedTestSuite struct {
+	suite.Suite
+}
+
+type typedPage struct {
+	Limit int `query:"limit"`
+}
+
+type typedRequest struct {
+	typedPage
+	ID   int64    `json:"-" param:"id"`
+	Name string   `json:"name"`
+	Tags []string `json:"-" query:"tag"`
+}
+
+var errTypedTaken = errors.New("name is taken")
+
+func (r *typedRequest) Validate() error {
+	switch r.Name {
+	case "":
+		return errors.New("name is required")
+	case "taken":
+		return fmt.Errorf("validate: %w", errTypedTaken)
+	case "invalid":
+		return fmt.Errorf("validate: %w", NewErrorString("invalid name", 422))
+	}
+	return nil
+}
+
+type typedResponse struct {
+	ID    int64    `json:"id"`
+	Name  string   `json:"name"`
+	Tags  []string `json:"tags"`
+	Limit int      `json:"limit"`
+}
+
+func typedUpdate(ctx *Ctx, in *typedRequest) (*typedResponse, error) {
+	if in.ID == 0 {
+		return nil, NewErrorString("not found", StatusNotFound)
+	}
+	if in.Name == "fail" {
+		return nil, errors.New("failed")
+	}
+	return &typedResponse{in.ID, in.Name, in.Tags, in.Limit}, nil
+}
+
+func (t *TypedTestSuite) client() (*Client, func()) {
+	ln := fasthttputil.NewInmemoryListener()
+	s := NewServer().SetListener(ln).
+		MapError(errTypedTaken, 409).
+		ControllerMethod(MethodPut, "/items/:id", Typed(typedUpdate)).
+		ControllerMethod(MethodGet, "/ping", Typed(func(*Ctx) (string, error) {
+			return "pong", nil
+		})).
+		ControllerMethod(MethodGet, "/nil/error", Typed(func(*Ctx) (*Error, error) { return nil, nil })).
+		ControllerMethod(MethodGet, "/nil/map", Typed(func(*Ctx) (map[string]int, error) { return nil, nil })).
+		ControllerMethod(MethodGet, "/nil/slice", Typed(func(*Ctx) ([]string, error) { return nil, nil })).
+		ControllerMethod(MethodGet, "/nil/marshaler", Typed(func(*Ctx) (json.Marshaler, error) { return nil, nil }))
+	go s.Listen()
+	return NewClient(ln.Addr().String()).
+		SetDialFunc(func(string) (net.Conn, error) { return ln.Dial() }), func() { ln.Close() }
+}
+
+func (t *TypedTestSuite) TestTyped() {
+	cl, closeFn := t.client()
+	defer closeFn()
+	rs, err := cl.Put("/items/5?tag=a&tag=b&limit=10", map[string]string{"name": "cat"})
+	t.NoError(err)
+	t.Equal(StatusOK, rs.StatusCode())
+	res := new(typedResponse)
+	t.NoError(rs.ReadJSON(res))
+	t.Equal(&typedResponse{5, "cat", []string{"a", "b"}, 10}, res)
+
+	rs, err = cl.Get("/ping")
+	t.NoError(err)
+	t.Equal(`"pong"`, string(rs.Body()))
+
+	for _, uri := range []string{"/nil/error", "/nil/map", "/nil/slice", "/nil/marshaler"} {
+		rs, err = cl.Get(uri)
+		t.NoError(err)
+		t.Equal(StatusOK, rs.StatusCode(), uri)
+		t.Equal("null", string(rs.Body()), uri)
+	}
+}
+
+func (t *TypedTestSuite) TestTypedErrors() {
+	cl, closeFn := t.client()
+	defer closeFn()
+	tests := []struct {
+		uri  string
+		body interface{}
+		code int
+		err  string
+	}{
+		{"/items/5", map[string]string{}, StatusBadRequest, "name is required"},
+		{"/items/x", map[string]string{"name": "cat"}, StatusBadRequest, "invalid value of id: x"},
+		{"/items/5?limit=x", map[string]string{"name": "cat"}, StatusBadRequest, "invalid value of limit: x"},
+		{"/items/5", BytesResult(`{`), StatusBadRequest, ""},
+		{"/items/5", map[string]string{"name": "taken"}, 409, "name is taken"},
+		{"/items/5", map[string]string{"name": "invalid"}, 422, "invalid name"},
+		{"/items/0", map[string]string{"name": "cat"}, StatusNotFound, "not found"},
+		{"/items/5", map[string]string{"name": "fail"}, StatusInternalServerError, "internal server error"},
+	}
+	for _, tt := range tests {
+		rs, err := cl.Put(tt.uri, tt.body)
+		t.NoError(err)
+		t.Equal(tt.code, rs.StatusCode(), tt.uri)
+		if tt.err != "" {
+			t.Contains(rs.Error().(*Error).Err, tt.err)
+		}
+	}
+}
+
+func (t *TypedTestSuite) TestTypedInvalid() {
+	t.Panics(func() { Typed(nil) })
+	t.Panics(func() { Typed(func(*Ctx) error { return nil }) })
+	t.Panics(func() { Typed(func(*Ctx, typedRequest) (int, error) { return 0, nil }) })
+	t.Panics(func() {
+		Typed(func(*Ctx, *struct {
+			M map[string]string `query:"m"`
+		}) (int, error) {
+			return 0, nil
+		})
+	})
+}
@@ -0,0 +1,257 @@
+package jsonapi
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fasthttp/websocket"
+	"github.com/valyala/fasthttp"
+)
+
+// WebSocket close codes
+const (
+	CloseNormalClosure     = websocket.CloseNormalClosure
+	CloseGoingAway         = websocket.CloseGoingAway
+	CloseProtocolError     = websocket.CloseProtocolError
+	CloseUnsupportedData   = websocket.CloseUnsupportedData
+	ClosePolicyViolation   = websocket.ClosePolicyViolation
+	CloseMessageTooBig     = websocket.CloseMessageTooBig
+	CloseInternalServerErr = websocket.CloseInternalServerErr
+
+	// closeErrorBase is added to Error.Code to get a close code
+	// in the range reserved for applications (4000-4999)
+	closeErrorBase = 4000
+
+	// maxCloseReason is the maximum close reason size in bytes
+	maxCloseReason = 123
+)
+
+// WebSocketHandler defines websocket handler func.
+// The connection is closed when the func returns: with CloseNormalClosure
+// for nil, 4000 + Code for *Error and errors mapped by server error
+// mappers and CloseInternalServerErr for other errors
+type WebSocketHandler func(*WSConn) error
+
+// WSConn is a wrapper for *websocket.Conn
+// it adds some useful methods for working with json
+type WSConn struct {
+	*websocket.Conn
+	values map[string]interface{}
+	mu     sync.Mutex
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newWSConn(conn *websocket.Conn, values map[string]interface{}) *WSConn {
+	return &WSConn{
+		Conn:   conn,
+		values: values,
+		done:   make(chan struct{}),
+	}
+}
+
+// ReadJSON reads the next message into v
+// A close frame sent for an *Error is returned as *Error
+func (c *WSConn) ReadJSON(v interface{}) error {
+	_, b, err := c.ReadMessage()
+	if err != nil {
+		return closeToError(err)
+	}
+	return Unmarshal(b, v)
+}
+
+// WriteJSON writes v as a text message
+// It is safe to call WriteJSON from multiple goroutines
+func (c *WSConn) WriteJSON(v interface{}) error {
+	b, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.WriteMessage(websocket.TextMessage, b)
+}
+
+// Ping sends a ping control message
+func (c *WSConn) Ping(timeout time.Duration) error {
+	return c.WriteControl(websocket.PingMessage, nil, time.Now().Add(timeout))
+}
+
+// KeepAlive pings the peer every interval and closes the connection
+// if nothing (including pong) is received during two intervals
+func (c *WSConn) KeepAlive(interval time.Duration) {
+	c.SetReadDeadline(time.Now().Add(2 * interval))
+	c.SetPongHandler(func(string) error {
+		return c.SetReadDeadline(time.Now().Add(2 * interval))
+	})
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-t.C:
+				if err := c.Ping(interval); err != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// CloseWith sends a close message with code and reason
+// and closes the connection
+func (c *WSConn) CloseWith(code int, reason string) error {
+	c.once.Do(func() { close(c.done) })
+	err := c.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(time.Second))
+	if cerr := c.Conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// CloseError closes the connection with a close code derived from err,
+// the reason is the message of *Error found in err chain. Other errors
+// are closed with CloseInternalServerErr without the error message
+func (c *WSConn) CloseError(err error) error {
+	if err == nil {
+		return c.CloseWith(CloseNormalClosure, "")
+	}
+	var e *Error
+	var ev Error
+	if !errors.As(err, &e) && errors.As(err, &ev) {
+		e = &ev
+	}
+	if e == nil || e.Code < 400 || e.Code >= 1000 {
+		return c.CloseWith(CloseInternalServerErr, ErrInternal.Error())
+	}
+	return c.CloseWith(closeErrorBase+e.Code, truncateUTF8(e.Err, maxCloseReason))
+}
+
+// truncateUTF8 truncates s to n bytes at a rune boundary
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// UserValue returns the request user value k copied on upgrade
+func (c *WSConn) UserValue(k string) interface{} {
+	return c.values[k]
+}
+
+// GetParamString returns path parameter k as string
+func (c *WSConn) GetParamString(k string) string {
+	v, _ := c.values[k].(string)
+	return v
+}
+
+// IsCloseError returns TRUE if err is a close error with one of codes
+func IsCloseError(err error, codes ...int) bool {
+	return websocket.IsCloseError(err, codes...)
+}
+
+func closeToError(err error) error {
+	var ce *websocket.CloseError
+	if errors.As(err, &ce) && ce.Code >= closeErrorBase+400 && ce.Code < closeErrorBase+1000 {
+		return NewErrorString(ce.Text, ce.Code-closeErrorBase)
+	}
+	return err
+}
+
+// SetWebSocketCheckOrigin sets a func checking websocket request origin.
+// By default requests with an Origin header not matching the Host are rejected
+func (s *Server) SetWebSocketCheckOrigin(fn func(*Ctx) bool) *Server {
+	s.wsUpgrader.CheckOrigin = func(ctx *fasthttp.RequestCtx) bool {
+		return fn(&Ctx{ctx})
+	}
+	return s
+}
+
+// WebSocket registers a websocket handler on path.
+// The server auth func is checked before the connection is upgraded.
+// Request data is not available after the upgrade, user values
+// (including path parameters) are copied to the connection.
+// A returned error is mapped by server error mappers and closes
+// the connection with the code of the error
+func (s *Server) WebSocket(p string, handler WebSocketHandler, opts ...RouteOption) *Server {
+	return s.Get(p, func(ctx *Ctx) {
+		values := map[string]interface{}{}
+		ctx.VisitUserValues(func(k []byte, v interface{}) {
+			values[string(k)] = v
+		})
+		s.wsUpgrader.Upgrade(ctx.RequestCtx, func(conn *websocket.Conn) {
+			c := newWSConn(conn, values)
+			err := handler(c)
+			if err != nil {
+				err = s.mapError(err)
+			}
+			c.CloseError(err)
+		})
+	}, append([]RouteOption{noBatch(ErrBatchUnsupported)}, opts...)...)
+}
+
+func newWebSocketUpgrader() *websocket.FastHTTPUpgrader {
+	return &websocket.FastHTTPUpgrader{
+		Error: func(ctx *fasthttp.RequestCtx, status int, reason error) {
+			(&Ctx{ctx}).Err(reason, status)
+		},
+	}
+}
+
+// WebSocket opens a websocket connection to uri
+func (c *Client) WebSocket(uri string) (*WSConn, error) {
+	r := c.Request()
+	if c.useSSL {
+		r.addr = "wss://" + c.addr
+	} else {
+		r.addr = "ws://" + c.addr
+	}
+	r.SetMethod(MethodGet).SetURI(uri)
+	if r.sign != nil {
+		r.sign(r)
+	}
+	h := http.Header{}
+	for k, v := range r.headers {
+		h.Set(k, v)
+	}
+	d := &websocket.Dialer{
+		HandshakeTimeout: 45 * time.Second,
+		Proxy:            http.ProxyFromEnvironment,
+		TLSClientConfig:  c.tlsConfig,
+	}
+	if c.dial != nil {
+		d.NetDial = func(_, addr string) (net.Conn, error) {
+			return c.dial(addr)
+		}
+	}
+	conn, res, err := d.Dial(r.makeURL(), h)
+	if err == websocket.ErrBadHandshake && res != nil {
+		// try to return the json error sent by the server
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(res.Body)
+		e := new(Error)
+		if Unmarshal(b, e) == nil && e.Err != "" {
+			if e.Code == 0 {
+				e.Code = res.StatusCode
+			}
+			return nil, e
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newWSConn(conn, map[string]interface{}{}), nil
+}
@@ -0,0 +1,157 @@
+package jsonapi
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestWebSocket(t *testing.T) {
+	suite.Run(t, new(WebSocketTestSuite))
+}
+
+type WebSocketTestSuite struct {
+	suite.Suite
+}
+
+func (t *WebSocketTestSuite) client(ln *fasthttputil.InmemoryListener) *Client {
+	return NewClient("ws.local").SetDialFunc(func(string) (net.Conn, error) {
+		return ln.Dial()
+	})
+}
+
+func (t *WebSocketTestSuite) TestEcho() {
+	ln, s := new(ServerTestSuite).getServer()
+	s.WebSocket("/ws/:room", func(c *WSConn) error {
+		for {
+			p := new(plainStruct)
+			if err := c.ReadJSON(p); err != nil {
+				if IsCloseError(err, CloseNormalClosure) {
+					return nil
+				}
+				return err
+			}
+			p.Name = c.GetParamString("room")
+			if err := c.WriteJSON(p); err != nil {
+				return err
+			}
+		}
+	})
+	go s.Listen()
+	defer ln.Close()
+	c, err := t.client(ln).WebSocket("/ws/lobby")
+	t.NoError(err)
+	for i := 1; i <= 3; i++ {
+		t.NoError(c.WriteJSON(plainStruct{ID: i}))
+		p := new(plainStruct)
+		t.NoError(c.ReadJSON(p))
+		t.Equal(&plainStruct{i, "lobby"}, p)
+	}
+	t.NoError(c.CloseWith(CloseNormalClosure, ""))
+}
+
+var errWSMissing = errors.New("missing")
+
+func (t *WebSocketTestSuite) TestCloseCodes() {
+	ln, s := new(ServerTestSuite).getServer()
+	s.WebSocket("/not-found", func(c *WSConn) error {
+		return NewErrorString("animal not found", StatusNotFound)
+	})
+	s.WebSocket("/fail", func(c *WSConn) error {
+		return errors.New("fail")
+	})
+	s.WebSocket("/wrapped", func(c *WSConn) error {
+		return fmt.Errorf("load: %w", NewErrorString(strings.Repeat("é", 100), 409))
+	})
+	s.WebSocket("/mapped", func(c *WSConn) error {
+		return fmt.Errorf("load: %w", errWSMissing)
+	})
+	s.MapError(errWSMissing, StatusNotFound)
+	go s.Listen()
+	defer ln.Close()
+	c, err := t.client(ln).WebSocket("/not-found")
+	t.NoError(err)
+	err = c.ReadJSON(new(plainStruct))
+	t.Equal(NewErrorString("animal not found", StatusNotFound), err)
+	c, err = t.client(ln).WebSocket("/fail")
+	t.NoError(err)
+	err = c.ReadJSON(new(plainStruct))
+	t.Equal(NewErrorString(ErrInternal.Error(), StatusInternalServerError), err)
+
+	// long reasons are truncated at a rune boundary
+	c, err = t.client(ln).WebSocket("/wrapped")
+	t.NoError(err)
+	err = c.ReadJSON(new(plainStruct))
+	t.Equal(NewErrorString(strings.Repeat("é", 61), 409), err)
+	c, err = t.client(ln).WebSocket("/mapped")
+	t.NoError(err)
+	err = c.ReadJSON(new(plainStruct))
+	t.Equal(NewErrorString("load: missing", StatusNotFound), err)
+}
+
+func (t *WebSocketTestSuite) TestAuth() {
+	ln, s := new(ServerTestSuite).getServer()
+	s.SetAuthFunc(func(ctx *Ctx) bool {
+		return ctx.GetHeader("Authorization") == "secret"
+	})
+	s.WebSocket("/ws", func(c *WSConn) error {
+		return nil
+	})
+	go s.Listen()
+	defer ln.Close()
+	_, err := t.client(ln).WebSocket("/ws")
+	t.IsType(&Error{}, err)
+	t.Equal(StatusUnauthorized, err.(*Error).Code)
+	c, err := t.client(ln).SetAuthFunc(func(r *Request) {
+		r.SetHeader("Authorization", "secret")
+	}).WebSocket("/ws")
+	t.NoError(err)
+	t.True(IsCloseError(c.ReadJSON(new(plainStruct)), CloseNormalClosure))
+}
+
+func (t *WebSocketTestSuite) TestCheckOrigin() {
+	ln, s := new(ServerTestSuite).getServer()
+	s.SetWebSocketCheckOrigin(func(*Ctx) bool { return false })
+	s.WebSocket("/ws", func(c *WSConn) error {
+		return nil
+	})
+	go s.Listen()
+	defer ln.Close()
+	_, err := t.client(ln).WebSocket("/ws")
+	t.IsType(&Error{}, err)
+	t.Equal(StatusForbidden, err.(*Error).Code)
+}
+
+func (t *WebSocketTestSuite) TestKeepAlive() {
+	ln, s := new(ServerTestSuite).getServer()
+	pongs := make(chan struct{}, 1)
+	s.WebSocket("/ws", func(c *WSConn) error {
+		c.KeepAlive(5 * time.Millisecond)
+		h := c.PongHandler()
+		c.SetPongHandler(func(data string) error {
+			select {
+			case pongs <- struct{}{}:
+			default:
+			}
+			return h(data)
+		})
+		return c.ReadJSON(new(plainStruct))
+	})
+	go s.Listen()
+	defer ln.Close()
+	c, err := t.client(ln).WebSocket("/ws")
+	t.NoError(err)
+	go c.ReadJSON(new(plainStruct)) // process pings
+	select {
+	case <-pongs:
+	case <-time.After(time.Second):
+		t.Fail("no pong received")
+	}
+	c.CloseWith(CloseNormalClosure, "")
+}